@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/EsteveSegura/BrickHauler/internal/capacity"
+	"github.com/EsteveSegura/BrickHauler/internal/metrics"
+	"github.com/EsteveSegura/BrickHauler/internal/output"
+	"github.com/EsteveSegura/BrickHauler/internal/runner"
+)
+
+// runFindCapacity steps the load up until the target breaks its objective
+// and reports the highest level that still meets it.
+func runFindCapacity(args []string) error {
+	fs := flag.NewFlagSet("find-capacity", flag.ContinueOnError)
+	var (
+		opts         options
+		mode         string
+		search       capacity.Search
+		stepDuration time.Duration
+		errorRate    float64
+	)
+	opts.register(fs)
+	fs.StringVar(&mode, "mode", "concurrency", "What to step up: concurrency (virtual users) or rate (requests per second, with --concurrent users)")
+	fs.IntVar(&search.Start, "start", 10, "First load level")
+	fs.IntVar(&search.Step, "step", 10, "Load added at each step of the ramp")
+	fs.IntVar(&search.Max, "max", 1000, "Highest load level to try")
+	fs.IntVar(&search.Precision, "precision", 1, "Stop the binary search once passing and failing levels are this close")
+	fs.DurationVar(&stepDuration, "step-duration", 30*time.Second, "How long each level runs")
+	fs.DurationVar(&search.SLO.P95, "slo-p95", 0, "Highest accepted 95th percentile latency (0 disables)")
+	fs.DurationVar(&search.SLO.P99, "slo-p99", 0, "Highest accepted 99th percentile latency (0 disables)")
+	fs.Float64Var(&errorRate, "slo-error-rate", 1, "Highest accepted share of failed requests, in percent")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := opts.resolveTarget(fs); err != nil {
+		return err
+	}
+	if opts.requests != 0 || opts.duration != 0 || opts.replay != "" || opts.profile != "" {
+		return fmt.Errorf("find-capacity runs every level for --step-duration; --request, --duration, --replay and --profile do not apply")
+	}
+	if stepDuration <= 0 {
+		return fmt.Errorf("--step-duration must be greater than 0")
+	}
+	search.SLO.MaxErrorRate = errorRate / 100
+
+	var unit string
+	switch mode {
+	case "concurrency":
+		unit = "users"
+	case "rate":
+		unit = "rps"
+		if opts.concurrency == 0 {
+			return fmt.Errorf("--concurrent is required in rate mode: it must be enough users to reach --max")
+		}
+	default:
+		return fmt.Errorf("invalid mode %q: must be concurrency or rate", mode)
+	}
+	if err := search.Validate(); err != nil {
+		return err
+	}
+
+	opts.duration = stepDuration
+	opts.liveFeed = false
+
+	// Build the configuration once up front so mistakes show before the
+	// first step runs.
+	if _, err := buildConfig(levelOptions(opts, mode, search.Start)); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	out := output.New(os.Stdout)
+	target := opts.uri
+	if opts.scenario != "" {
+		target = opts.scenario
+	}
+	out.PrintCapacityHeader(target, unit, search.SLO, stepDuration)
+
+	search.OnStep = out.PrintCapacityStep
+	search.Probe = func(ctx context.Context, level int) (metrics.Snapshot, time.Duration, error) {
+		cfg, err := buildConfig(levelOptions(opts, mode, level))
+		if err != nil {
+			return metrics.Snapshot{}, 0, err
+		}
+		r := runner.New(cfg, io.Discard)
+		if err := r.Run(ctx); err != nil {
+			return metrics.Snapshot{}, 0, err
+		}
+		snap, elapsed := r.Result()
+		return snap, elapsed, nil
+	}
+
+	res, err := search.Run(ctx)
+	if res != nil {
+		out.PrintCapacity(res, unit)
+	}
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// levelOptions returns opts set to run at the given load level.
+func levelOptions(opts options, mode string, level int) options {
+	if mode == "rate" {
+		opts.maxRPS = float64(level)
+	} else {
+		opts.concurrency = level
+	}
+	return opts
+}
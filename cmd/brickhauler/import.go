@@ -0,0 +1,204 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/EsteveSegura/BrickHauler/internal/curl"
+	"github.com/EsteveSegura/BrickHauler/internal/har"
+	"github.com/EsteveSegura/BrickHauler/internal/openapi"
+	"github.com/EsteveSegura/BrickHauler/internal/scenario"
+)
+
+// runImport converts recordings from other tools into scenario files.
+func runImport(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: brickhauler import <har|curl|openapi> [options] <input>")
+	}
+
+	switch args[0] {
+	case "har":
+		return importHAR(args[1:])
+	case "curl":
+		return importCurl(args[1:])
+	case "openapi":
+		return importOpenAPI(args[1:])
+	default:
+		return fmt.Errorf("unknown import format %q: must be har, curl or openapi", args[0])
+	}
+}
+
+func importHAR(args []string) error {
+	fs := flag.NewFlagSet("import har", flag.ContinueOnError)
+	var (
+		output        string
+		excludes      stringSlice
+		includeStatic bool
+	)
+	fs.StringVar(&output, "o", "", "Write the scenario to this file instead of stdout")
+	fs.Var(&excludes, "exclude", "Skip requests whose URL matches this regular expression (repeatable)")
+	fs.BoolVar(&includeStatic, "include-static", false, "Keep images, stylesheets, scripts and fonts")
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: brickhauler import har [options] <session.har>")
+	}
+
+	opts := har.Options{}
+	if !includeStatic {
+		opts.Exclude = append(opts.Exclude, har.StaticAssets)
+	}
+	for _, e := range excludes {
+		re, err := regexp.Compile(e)
+		if err != nil {
+			return fmt.Errorf("invalid --exclude pattern %q: %w", e, err)
+		}
+		opts.Exclude = append(opts.Exclude, re)
+	}
+
+	f, err := os.Open(positional[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	capture, err := har.Parse(f)
+	if err != nil {
+		return err
+	}
+
+	sc, err := capture.Scenario(opts)
+	if err != nil {
+		return err
+	}
+
+	return writeScenario(sc, output)
+}
+
+func importCurl(args []string) error {
+	fs := flag.NewFlagSet("import curl", flag.ContinueOnError)
+	var output string
+	fs.StringVar(&output, "o", "", "Write the scenario to this file instead of stdout")
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: brickhauler import curl [options] '<curl command>'")
+	}
+
+	cmd, err := curl.Parse(positional[0])
+	if err != nil {
+		return err
+	}
+
+	sc := &scenario.Scenario{Targets: []scenario.Target{cmd.Target()}}
+	if err := writeScenario(sc, output); err != nil {
+		return err
+	}
+
+	// Connection options live on the command line, not in the scenario.
+	if flags := cmd.RunFlags(); len(flags) > 0 {
+		fmt.Fprintf(os.Stderr, "Run with: %s\n", strings.Join(flags, " "))
+	}
+	return nil
+}
+
+func importOpenAPI(args []string) error {
+	fs := flag.NewFlagSet("import openapi", flag.ContinueOnError)
+	var (
+		output     string
+		opts       openapi.Options
+		operations stringSlice
+		tags       stringSlice
+		excludes   stringSlice
+	)
+	fs.StringVar(&output, "o", "", "Write the scenario to this file instead of stdout")
+	fs.StringVar(&opts.Server, "server", "", "Base URL to send requests to (default is the first server in the document)")
+	fs.Var(&operations, "operation", "Import only this operation, by operationId or 'METHOD /path' (repeatable)")
+	fs.Var(&tags, "tag", "Import only operations with this tag (repeatable)")
+	fs.Var(&excludes, "exclude", "Skip operations whose 'METHOD /path' matches this regular expression (repeatable)")
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: brickhauler import openapi [options] <openapi.json>")
+	}
+
+	opts.Operations = operations
+	opts.Tags = tags
+	for _, e := range excludes {
+		re, err := regexp.Compile(e)
+		if err != nil {
+			return fmt.Errorf("invalid --exclude pattern %q: %w", e, err)
+		}
+		opts.Exclude = append(opts.Exclude, re)
+	}
+
+	f, err := os.Open(positional[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	doc, err := openapi.Parse(f)
+	if err != nil {
+		return err
+	}
+
+	sc, err := doc.Scenario(opts)
+	if err != nil {
+		return err
+	}
+
+	return writeScenario(sc, output)
+}
+
+// writeScenario writes sc to path, or to stdout when path is empty.
+func writeScenario(sc *scenario.Scenario, path string) error {
+	var w io.Writer = os.Stdout
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := sc.Write(w); err != nil {
+		return fmt.Errorf("writing scenario: %w", err)
+	}
+
+	if path != "" {
+		fmt.Fprintf(os.Stderr, "Wrote %d requests to %s\n", len(sc.Targets), path)
+	}
+	return nil
+}
+
+// parseInterspersed parses flags that may appear before or after the
+// positional arguments and returns the positional ones.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
@@ -4,14 +4,22 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"net/url"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
+	"github.com/EsteveSegura/BrickHauler/internal/accesslog"
 	"github.com/EsteveSegura/BrickHauler/internal/config"
+	"github.com/EsteveSegura/BrickHauler/internal/control"
+	"github.com/EsteveSegura/BrickHauler/internal/curl"
+	"github.com/EsteveSegura/BrickHauler/internal/prometheus"
 	"github.com/EsteveSegura/BrickHauler/internal/runner"
+	"github.com/EsteveSegura/BrickHauler/internal/scenario"
+	"github.com/EsteveSegura/BrickHauler/internal/sink"
 	"github.com/EsteveSegura/BrickHauler/internal/version"
 )
 
@@ -34,31 +42,88 @@ func main() {
 	}
 }
 
+// options holds the raw command line values before validation.
+type options struct {
+	method      string
+	uri         string
+	concurrency int
+	requests    int
+	duration    time.Duration
+	warmup      string
+	profile     string
+	cookies     stringSlice
+	headers     stringSlice
+	body        string
+	bodyFile    string
+	scenario    string
+	fromCurl    string
+	replay      string
+	replaySpeed string
+	thinkTime   string
+	pacing      time.Duration
+	maxRPS      float64
+	proxies     stringSlice
+	proxyFile   string
+	proxyAuth   string
+	rotation    string
+	liveFeed    bool
+	transport   config.Transport
+	tls         config.TLSOptions
+	httpVersion string
+	http2       config.HTTP2
+	isolate     bool
+	resolves    stringSlice
+	unixSocket  string
+	sourceIPs   stringSlice
+	// adjustable is set by the commands that change the load mid-run.
+	adjustable bool
+}
+
 func run() error {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "import":
+			return runImport(os.Args[2:])
+		case "record":
+			return runRecord(os.Args[2:])
+		case "find-capacity":
+			return runFindCapacity(os.Args[2:])
+		}
+	}
+
 	var (
-		method      string
-		uri         string
-		concurrency int
-		requests    int
-		cookies     stringSlice
-		proxy       string
-		liveFeed    bool
+		opts        options
 		showVersion bool
+		interactive bool
+		adjustStep  int
+		controlAddr string
+		allowRemote bool
+		apiAdjust   bool
+		metricsAddr string
+		sinks       stringSlice
+		sinkEvery   time.Duration
 	)
 
-	flag.StringVar(&method, "verb", "GET", "HTTP method (GET, POST, PUT, PATCH, DELETE, etc.)")
-	flag.StringVar(&uri, "uri", "", "Target URL for load testing")
-	flag.IntVar(&concurrency, "concurrent", 0, "Number of concurrent virtual users")
-	flag.IntVar(&requests, "request", 0, "Total number of requests to send")
-	flag.Var(&cookies, "cookie", "Cookie in name=value format (repeatable)")
-	flag.StringVar(&proxy, "proxy", "", "HTTP proxy URL")
-	flag.BoolVar(&liveFeed, "feed", false, "Show real-time progress")
+	opts.register(flag.CommandLine)
+	flag.BoolVar(&interactive, "interactive", false, "Change or pause the load while running with commands on stdin (type help) and SIGUSR1/SIGUSR2; when stdin is a terminal, Ctrl+Z (SIGTSTP) pauses instead of suspending")
+	flag.StringVar(&controlAddr, "control-addr", "", "Serve an HTTP API to watch and stop the test (and adjust it with --control-adjust) on this address, such as 127.0.0.1:9000")
+	flag.BoolVar(&allowRemote, "control-allow-remote", false, "Let --control-addr listen on an address other machines can reach; the API has no authentication")
+	flag.BoolVar(&apiAdjust, "control-adjust", false, "Let --control-addr change the users and rate and pause the test, making the load adjustable as with --interactive")
+	flag.StringVar(&metricsAddr, "metrics-addr", "", "Serve live Prometheus metrics at /metrics on this address, such as 127.0.0.1:9100")
+	flag.Var(&sinks, "sink", "Stream per-interval metrics over UDP to statsd://host:port or influx://host:port, with an optional ?prefix= (repeatable)")
+	flag.DurationVar(&sinkEvery, "sink-interval", 10*time.Second, "How often --sink sends the metrics of the last interval")
+	flag.IntVar(&adjustStep, "adjust-step", 10, "Virtual users added by SIGUSR1 or + and removed by SIGUSR2 or -")
 	flag.BoolVar(&showVersion, "v", false, "Show version")
 	flag.BoolVar(&showVersion, "version", false, "Show version")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "BrickHauler %s - HTTP Load Testing Tool\n\n", version.Version)
-		fmt.Fprintf(os.Stderr, "Usage: brickhauler [options]\n\nOptions:\n")
+		fmt.Fprintf(os.Stderr, "Usage: brickhauler [options]\n")
+		fmt.Fprintf(os.Stderr, "       brickhauler import har [options] <session.har>\n")
+		fmt.Fprintf(os.Stderr, "       brickhauler import curl [options] '<curl command>'\n")
+		fmt.Fprintf(os.Stderr, "       brickhauler import openapi [options] <openapi.json>\n")
+		fmt.Fprintf(os.Stderr, "       brickhauler record [--listen 127.0.0.1:8888] [options]\n")
+		fmt.Fprintf(os.Stderr, "       brickhauler find-capacity [options] (see find-capacity -h)\n\nOptions:\n")
 		flag.PrintDefaults()
 	}
 
@@ -70,18 +135,35 @@ func run() error {
 	}
 
 	// Validate required flags
-	if uri == "" {
-		return fmt.Errorf("--uri is required")
+	if err := opts.resolveTarget(flag.CommandLine); err != nil {
+		return err
 	}
-	if concurrency == 0 {
+	if opts.concurrency == 0 && opts.profile == "" {
 		return fmt.Errorf("--concurrent is required")
 	}
-	if requests == 0 {
-		return fmt.Errorf("--request is required")
+	if opts.requests == 0 && opts.duration == 0 && opts.replay == "" {
+		return fmt.Errorf("--request or --duration is required")
 	}
 
+	if adjustStep <= 0 {
+		return fmt.Errorf("--adjust-step must be greater than 0")
+	}
+	if sinkEvery <= 0 {
+		return fmt.Errorf("--sink-interval must be greater than 0")
+	}
+
+	if controlAddr != "" && !allowRemote && !isLoopback(controlAddr) {
+		return fmt.Errorf("--control-addr %s can be reached from other machines and the API has no authentication; use a loopback address such as 127.0.0.1:9000 or add --control-allow-remote", controlAddr)
+	}
+
+	if apiAdjust && controlAddr == "" {
+		return fmt.Errorf("--control-adjust requires --control-addr")
+	}
+
+	opts.adjustable = interactive || apiAdjust
+
 	// Build and validate config
-	cfg, err := buildConfig(method, uri, concurrency, requests, cookies, proxy, liveFeed)
+	cfg, err := buildConfig(opts)
 	if err != nil {
 		return err
 	}
@@ -101,31 +183,378 @@ func run() error {
 
 	// Run the load test
 	r := runner.New(cfg, os.Stdout)
+	if interactive {
+		fmt.Fprintln(os.Stderr, control.Help)
+		go control.ReadCommands(os.Stdin, os.Stderr, r, adjustStep)
+		control.NotifySignals(ctx, os.Stderr, r, adjustStep, isTerminal(os.Stdin))
+	}
+	if controlAddr != "" {
+		api := control.NewHandler(r, func(source string) {
+			fmt.Fprintf(os.Stderr, "\nStopping at the request of the %s...\n", source)
+			r.Stop(source)
+		})
+		closeAPI, err := serve("Control API", controlAddr, api)
+		if err != nil {
+			return err
+		}
+		defer closeAPI()
+	}
+	if metricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", newExporter(r))
+		closeMetrics, err := serve("Prometheus metrics", metricsAddr, mux)
+		if err != nil {
+			return err
+		}
+		defer closeMetrics()
+	}
+	if len(sinks) > 0 {
+		reporter, err := newReporter(r, sinks, sinkEvery)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := reporter.Stop(); err != nil {
+				fmt.Fprintf(os.Stderr, "metrics sink: %v\n", err)
+			}
+		}()
+	}
 	return r.Run(ctx)
 }
 
-func buildConfig(method, uri string, concurrency, requests int, cookies stringSlice, proxy string, liveFeed bool) (*config.Config, error) {
-	parsedMethod, err := config.ParseHTTPMethod(method)
+// serve starts an HTTP server for h on addr in the background and returns
+// a function that closes it.
+func serve(name, addr string, h http.Handler) (func() error, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	srv := &http.Server{Handler: h}
+	go srv.Serve(ln)
+	fmt.Fprintf(os.Stderr, "%s listening on http://%s\n", name, ln.Addr())
+	return srv.Close, nil
+}
+
+// isTerminal reports whether f is a terminal rather than a pipe or file.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// isLoopback reports whether addr only accepts connections from this
+// machine.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// newExporter returns a Prometheus exporter fed by r, with gauges for its
+// current load.
+func newExporter(r *runner.Runner) *prometheus.Exporter {
+	exp := prometheus.New()
+	exp.Gauge("brickhauler_active_users", "Virtual users the run is asking for.", func() float64 {
+		return float64(r.Users())
+	})
+	exp.Gauge("brickhauler_max_rps", "Requests per second cap, 0 when unlimited.", r.MaxRPS)
+	exp.Gauge("brickhauler_paused", "1 while the run is paused.", func() float64 {
+		if r.Paused() {
+			return 1
+		}
+		return 0
+	})
+	r.Observe(exp)
+	return exp
+}
+
+// newReporter opens every sink and returns a reporter fed by r, which
+// starts it once the measured run begins.
+func newReporter(r *runner.Runner, specs []string, interval time.Duration) (*sink.Reporter, error) {
+	var sinks []*sink.Sink
+	for _, spec := range specs {
+		s, err := sink.Dial(spec)
+		if err != nil {
+			for _, opened := range sinks {
+				opened.Close()
+			}
+			return nil, fmt.Errorf("--sink: %w", err)
+		}
+		sinks = append(sinks, s)
+	}
+
+	reporter := sink.NewReporter(sinks, interval, r.Users)
+	r.Observe(reporter)
+	return reporter, nil
+}
+
+// register defines the flags describing a load test run on fs.
+func (o *options) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.method, "verb", "GET", "HTTP method (GET, POST, PUT, PATCH, DELETE, etc.)")
+	fs.StringVar(&o.uri, "uri", "", "Target URL for load testing (http://, https:// or unix:///path/to.sock)")
+	fs.IntVar(&o.concurrency, "concurrent", 0, "Number of concurrent virtual users")
+	fs.IntVar(&o.requests, "request", 0, "Total number of requests to send")
+	fs.DurationVar(&o.duration, "duration", 0, "Run for this long (with --request, stop at whichever comes first)")
+	fs.StringVar(&o.warmup, "warmup", "", "Send unmeasured traffic first, for a duration (30s) or a number of requests (500)")
+	fs.StringVar(&o.profile, "profile", "", "Vary the virtual users over the run: spike:10,200,1m,30s, step:10,10,30s,100, sine:10,100,2m or csv:profile.csv")
+	fs.Var(&o.cookies, "cookie", "Cookie in name=value format (repeatable)")
+	fs.Var(&o.headers, "header", "Request header in 'Name: value' format (repeatable)")
+	fs.StringVar(&o.body, "body", "", "Request body")
+	fs.StringVar(&o.bodyFile, "body-file", "", "File whose contents are sent as the request body")
+	fs.StringVar(&o.scenario, "scenario", "", "JSON scenario file with several weighted targets (replaces --uri)")
+	fs.StringVar(&o.replay, "replay", "", "Replay an access log (combined format or JSON lines) against the --uri host")
+	fs.StringVar(&o.replaySpeed, "replay-speed", "1x", "Replay speed multiplier, such as 2x or 0.5x")
+	fs.StringVar(&o.fromCurl, "from-curl", "", "Take the request from a curl command line; other flags override its options")
+	fs.Var(&o.proxies, "proxy", "Proxy URL (http, https, socks5 or socks5h; repeatable); defaults to HTTP_PROXY/HTTPS_PROXY")
+	fs.StringVar(&o.proxyFile, "proxy-file", "", "File with one proxy URL per line")
+	fs.StringVar(&o.rotation, "proxy-rotation", "round-robin", "How requests are assigned to proxies: round-robin, random or sticky (per virtual user)")
+	fs.StringVar(&o.proxyAuth, "proxy-auth", "", "Proxy credentials in user:password format for proxies without their own")
+	fs.StringVar(&o.thinkTime, "think-time", "", "Pause after each request: 500ms, 200ms-800ms (uniform), normal:500ms,100ms or exp:500ms")
+	fs.DurationVar(&o.pacing, "pacing", 0, "Start each iteration of a virtual user this often, regardless of response time")
+	fs.Float64Var(&o.maxRPS, "max-rps", 0, "Cap the requests per second across all virtual users (0 means no limit)")
+	fs.BoolVar(&o.liveFeed, "feed", false, "Show real-time progress")
+	fs.DurationVar(&o.transport.Timeout, "timeout", 30*time.Second, "Overall timeout per request")
+	fs.DurationVar(&o.transport.ConnectTimeout, "connect-timeout", 30*time.Second, "Timeout for establishing a TCP connection")
+	fs.DurationVar(&o.transport.TLSHandshakeTimeout, "tls-handshake-timeout", 10*time.Second, "Timeout for the TLS handshake")
+	fs.DurationVar(&o.transport.ResponseHeaderTimeout, "response-header-timeout", 0, "Timeout waiting for response headers (0 disables)")
+	fs.IntVar(&o.transport.MaxIdleConns, "max-idle-conns", 0, "Idle connections kept for reuse (default matches --concurrent)")
+	fs.IntVar(&o.transport.MaxConnsPerHost, "max-conns-per-host", 0, "Maximum connections per host (default matches --concurrent)")
+	fs.BoolVar(&o.transport.DisableKeepAlives, "disable-keepalive", false, "Open a new connection for every request")
+	fs.DurationVar(&o.transport.DNSCacheTTL, "dns-cache-ttl", 0, "Cache DNS lookups per client for this long (0 resolves on every connection)")
+	fs.Var(&o.resolves, "resolve", "Connect host:port to addr[,addr...] instead of resolving it (repeatable)")
+	fs.StringVar(&o.unixSocket, "unix-socket", "", "Send requests over this unix socket, using --uri for the Host and path")
+	fs.Var(&o.sourceIPs, "source-ip", "Local address to send requests from (repeatable; virtual users are spread across them)")
+	fs.BoolVar(&o.isolate, "isolate-connections", false, "Give each virtual user its own connections and TLS sessions, and its own DNS cache with --dns-cache-ttl")
+	fs.StringVar(&o.tls.CertFile, "cert", "", "Client certificate file (PEM) for mutual TLS")
+	fs.StringVar(&o.tls.KeyFile, "key", "", "Client private key file (PEM) for mutual TLS")
+	fs.StringVar(&o.tls.CAFile, "cacert", "", "CA bundle file (PEM) used to verify the server")
+	fs.BoolVar(&o.tls.Insecure, "insecure", false, "Skip TLS certificate verification")
+	fs.StringVar(&o.tls.ServerName, "sni", "", "Server name sent in the TLS handshake")
+	fs.StringVar(&o.tls.MinVersion, "tls-min-version", "", "Minimum TLS version (1.0, 1.1, 1.2, 1.3)")
+	fs.StringVar(&o.tls.MaxVersion, "tls-max-version", "", "Maximum TLS version (1.0, 1.1, 1.2, 1.3)")
+	fs.StringVar(&o.tls.CipherSuites, "ciphers", "", "Comma separated TLS 1.0-1.2 cipher suites")
+	fs.StringVar(&o.httpVersion, "http-version", "", "HTTP version: 1.1, 2 or h2c (default negotiates HTTP/2 over TLS)")
+	fs.IntVar(&o.http2.MaxReadFrameSize, "h2-max-read-frame-size", 0, "Largest HTTP/2 frame accepted, in bytes")
+	fs.IntVar(&o.http2.MaxReceiveBufferPerStream, "h2-stream-window", 0, "HTTP/2 per-stream flow control window, in bytes")
+	fs.IntVar(&o.http2.MaxReceiveBufferPerConnection, "h2-conn-window", 0, "HTTP/2 per-connection flow control window, in bytes")
+}
+
+// resolveTarget applies --from-curl and checks that exactly one source of
+// requests was given.
+func (o *options) resolveTarget(fs *flag.FlagSet) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	if o.fromCurl != "" {
+		cmd, err := curl.Parse(o.fromCurl)
+		if err != nil {
+			return fmt.Errorf("--from-curl: %w", err)
+		}
+		applyCurl(o, cmd, explicit)
+	}
+
+	if o.uri == "" && o.scenario == "" {
+		return fmt.Errorf("--uri or --scenario is required")
+	}
+	if o.uri != "" && o.scenario != "" {
+		return fmt.Errorf("--uri and --scenario cannot be used together")
+	}
+	if o.scenario != "" {
+		// Every scenario target sets its own method and body.
+		for _, name := range []string{"verb", "body", "body-file"} {
+			if explicit[name] {
+				return fmt.Errorf("--%s and --scenario cannot be used together; set it on the scenario's targets", name)
+			}
+		}
+	}
+	if o.replay != "" && o.uri == "" {
+		return fmt.Errorf("--replay requires --uri for the host to replay against")
+	}
+	return nil
+}
+
+// applyCurl fills opts from a parsed curl command. Flags given explicitly on
+// the command line win over the curl options.
+func applyCurl(opts *options, cmd *curl.Command, explicit map[string]bool) {
+	set := func(name string, dst *string, value string) {
+		if !explicit[name] && value != "" {
+			*dst = value
+		}
+	}
+
+	set("verb", &opts.method, cmd.Method)
+	if opts.scenario == "" {
+		set("uri", &opts.uri, cmd.URL)
+	}
+	if !explicit["body-file"] {
+		set("body", &opts.body, cmd.Body)
+	}
+	set("proxy-auth", &opts.proxyAuth, cmd.ProxyAuth)
+	set("cacert", &opts.tls.CAFile, cmd.CACert)
+	set("cert", &opts.tls.CertFile, cmd.Cert)
+	set("key", &opts.tls.KeyFile, cmd.Key)
+	set("http-version", &opts.httpVersion, cmd.HTTPVersion)
+	set("unix-socket", &opts.unixSocket, cmd.UnixSocket)
+
+	if !explicit["proxy"] && !explicit["proxy-file"] && cmd.Proxy != "" {
+		opts.proxies = stringSlice{cmd.Proxy}
+	}
+	if !explicit["insecure"] && cmd.Insecure {
+		opts.tls.Insecure = true
+	}
+	if !explicit["timeout"] && cmd.Timeout > 0 {
+		opts.transport.Timeout = cmd.Timeout
+	}
+	if !explicit["connect-timeout"] && cmd.ConnTimeout > 0 {
+		opts.transport.ConnectTimeout = cmd.ConnTimeout
+	}
+
+	// Repeatable flags add to the curl values; a --header replaces a curl
+	// header of the same name.
+	var headers stringSlice
+	for _, h := range cmd.Headers {
+		name, _, _ := strings.Cut(h, ":")
+		if !hasHeader(opts.headers, name) {
+			headers = append(headers, h)
+		}
+	}
+	opts.headers = append(headers, opts.headers...)
+	opts.cookies = append(stringSlice(cmd.Cookies), opts.cookies...)
+	opts.resolves = append(stringSlice(cmd.Resolve), opts.resolves...)
+}
+
+// hasHeader reports whether headers, in "Name: value" form, include name.
+func hasHeader(headers []string, name string) bool {
+	name = strings.TrimSpace(name)
+	for _, h := range headers {
+		if n, _, _ := strings.Cut(h, ":"); strings.EqualFold(strings.TrimSpace(n), name) {
+			return true
+		}
+	}
+	return false
+}
+
+func buildConfig(opts options) (*config.Config, error) {
+	parsedMethod, err := config.ParseHTTPMethod(opts.method)
 	if err != nil {
 		return nil, err
 	}
 
-	parsedURI, err := config.NewURI(uri)
+	var (
+		parsedURI config.URI
+		targets   []config.Target
+		sequence  bool
+	)
+	if opts.scenario != "" {
+		sc, err := scenario.Load(opts.scenario)
+		if err != nil {
+			return nil, err
+		}
+		if targets, err = sc.ConfigTargets(); err != nil {
+			return nil, err
+		}
+		sequence = sc.Sequential()
+	} else {
+		if parsedURI, err = config.NewURI(opts.uri); err != nil {
+			return nil, err
+		}
+	}
+
+	headers, err := config.ParseHeaders(opts.headers)
 	if err != nil {
 		return nil, err
 	}
 
-	parsedCookies, err := config.ParseCookies(cookies)
+	body := []byte(opts.body)
+	if opts.bodyFile != "" {
+		if opts.body != "" {
+			return nil, fmt.Errorf("--body and --body-file cannot be used together")
+		}
+		if body, err = os.ReadFile(opts.bodyFile); err != nil {
+			return nil, fmt.Errorf("reading body file: %w", err)
+		}
+	}
+
+	var replay *config.Replay
+	requests := opts.requests
+	if opts.replay != "" {
+		if replay, err = loadReplay(opts, parsedURI); err != nil {
+			return nil, err
+		}
+		requests = len(replay.Requests)
+	}
+
+	parsedCookies, err := config.ParseCookies(opts.cookies)
 	if err != nil {
 		return nil, err
 	}
 
-	var proxyURL *url.URL
-	if proxy != "" {
-		proxyURL, err = url.Parse(proxy)
+	rawProxies := opts.proxies
+	if opts.proxyFile != "" {
+		fromFile, err := config.LoadProxies(opts.proxyFile)
 		if err != nil {
-			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+			return nil, err
 		}
+		rawProxies = append(rawProxies, fromFile...)
+	}
+	if len(rawProxies) == 0 && opts.proxyAuth != "" {
+		return nil, fmt.Errorf("--proxy-auth requires --proxy or --proxy-file")
+	}
+
+	proxies, err := config.ParseProxyURLs(rawProxies, opts.proxyAuth)
+	if err != nil {
+		return nil, err
+	}
+
+	rotation, err := config.ParseProxyRotation(opts.rotation)
+	if err != nil {
+		return nil, err
+	}
+
+	resolve, err := config.ParseResolves(opts.resolves)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceIPs, err := config.ParseSourceIPs(opts.sourceIPs)
+	if err != nil {
+		return nil, err
+	}
+
+	httpVersion, err := config.ParseHTTPVersion(opts.httpVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	thinkTime, err := config.ParseThinkTime(opts.thinkTime)
+	if err != nil {
+		return nil, err
+	}
+
+	warmup, err := config.ParseWarmup(opts.warmup)
+	if err != nil {
+		return nil, err
+	}
+
+	// A profile needs as many virtual users as its peak, unless
+	// --concurrent says otherwise.
+	profile, err := config.ParseProfile(opts.profile)
+	if err != nil {
+		return nil, err
+	}
+	concurrency := opts.concurrency
+	if profile != nil && concurrency == 0 {
+		concurrency = profile.MaxUsers()
+	}
+
+	tlsConfig, err := config.NewTLSConfig(opts.tls)
+	if err != nil {
+		return nil, err
 	}
 
 	cfg := &config.Config{
@@ -134,8 +563,30 @@ func buildConfig(method, uri string, concurrency, requests int, cookies stringSl
 		Concurrency: concurrency,
 		Requests:    requests,
 		Cookies:     parsedCookies,
-		ProxyURL:    proxyURL,
-		LiveFeed:    liveFeed,
+		Headers:     headers,
+		Body:        body,
+		Proxies:     proxies,
+		LiveFeed:    opts.liveFeed,
+		Transport:   opts.transport,
+		TLSConfig:   tlsConfig,
+		HTTPVersion: httpVersion,
+		HTTP2:       opts.http2,
+
+		IsolateConnections: opts.isolate,
+		Resolve:            resolve,
+		UnixSocket:         opts.unixSocket,
+		ProxyRotation:      rotation,
+		SourceIPs:          sourceIPs,
+		Targets:            targets,
+		Sequence:           sequence,
+		Replay:             replay,
+		ThinkTime:          thinkTime,
+		Pacing:             opts.pacing,
+		MaxRPS:             opts.maxRPS,
+		Duration:           opts.duration,
+		Warmup:             warmup,
+		Profile:            profile,
+		Adjustable:         opts.adjustable,
 	}
 
 	if err := cfg.Validate(); err != nil {
@@ -144,3 +595,32 @@ func buildConfig(method, uri string, concurrency, requests int, cookies stringSl
 
 	return cfg, nil
 }
+
+// loadReplay reads the access log to replay against base. --request, when
+// given, replays only the first requests of the log.
+func loadReplay(opts options, base config.URI) (*config.Replay, error) {
+	speed, err := config.ParseReplaySpeed(opts.replaySpeed)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(opts.replay)
+	if err != nil {
+		return nil, fmt.Errorf("reading access log: %w", err)
+	}
+	defer f.Close()
+
+	log, err := accesslog.Parse(f, base)
+	if err != nil {
+		return nil, err
+	}
+	if log.Skipped > 0 {
+		fmt.Fprintf(os.Stderr, "Skipped %d access log lines that are not replayable requests\n", log.Skipped)
+	}
+
+	requests := log.Requests
+	if opts.requests > 0 && opts.requests < len(requests) {
+		requests = requests[:opts.requests]
+	}
+	return &config.Replay{Requests: requests, Speed: speed}, nil
+}
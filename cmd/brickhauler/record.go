@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"regexp"
+	"syscall"
+
+	"github.com/EsteveSegura/BrickHauler/internal/har"
+	"github.com/EsteveSegura/BrickHauler/internal/record"
+)
+
+// runRecord runs a recording proxy until interrupted and writes the
+// captured requests as a scenario.
+func runRecord(args []string) error {
+	fs := flag.NewFlagSet("record", flag.ContinueOnError)
+	var (
+		listen        string
+		output        string
+		caCert        string
+		caKey         string
+		insecure      bool
+		excludes      stringSlice
+		includeStatic bool
+	)
+	fs.StringVar(&listen, "listen", "127.0.0.1:8888", "Address the proxy listens on; only this machine can use it unless you pick a wider one, such as :8888")
+	fs.StringVar(&output, "o", "", "Write the scenario to this file instead of stdout")
+	fs.StringVar(&caCert, "ca-cert", "brickhauler-ca.pem", "CA certificate used to intercept HTTPS; created if missing")
+	fs.StringVar(&caKey, "ca-key", "brickhauler-ca-key.pem", "Private key of the CA certificate; created if missing")
+	fs.BoolVar(&insecure, "insecure", false, "Skip TLS verification of the real servers")
+	fs.Var(&excludes, "exclude", "Skip requests whose URL matches this regular expression (repeatable)")
+	fs.BoolVar(&includeStatic, "include-static", false, "Keep images, stylesheets, scripts and fonts")
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return fmt.Errorf("usage: brickhauler record [options]")
+	}
+
+	opts := record.Options{}
+	if !includeStatic {
+		opts.Exclude = append(opts.Exclude, har.StaticAssets)
+	}
+	for _, e := range excludes {
+		re, err := regexp.Compile(e)
+		if err != nil {
+			return fmt.Errorf("invalid --exclude pattern %q: %w", e, err)
+		}
+		opts.Exclude = append(opts.Exclude, re)
+	}
+
+	ca, err := loadOrCreateCA(caCert, caKey)
+	if err != nil {
+		return err
+	}
+
+	var upstreamTLS *tls.Config
+	if insecure {
+		upstreamTLS = &tls.Config{InsecureSkipVerify: true}
+	}
+	rec := record.New(ca, upstreamTLS)
+	defer rec.Close()
+
+	ln, err := net.Listen("tcp", listen)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: rec}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+
+	fmt.Fprintf(os.Stderr, "Recording proxy listening on %s (trust %s on clients to record HTTPS)\n", ln.Addr(), caCert)
+	fmt.Fprintln(os.Stderr, "Press Ctrl+C to stop and write the scenario.")
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "\nRecorded %d requests\n", rec.Len())
+	sc, err := rec.Scenario(opts)
+	if err != nil {
+		return err
+	}
+	return writeScenario(sc, output)
+}
+
+// loadOrCreateCA loads the recording CA, generating and saving a new one
+// the first time.
+func loadOrCreateCA(certFile, keyFile string) (*record.CA, error) {
+	if _, err := os.Stat(certFile); err == nil {
+		return record.LoadCA(certFile, keyFile)
+	}
+
+	ca, err := record.NewCA()
+	if err != nil {
+		return nil, err
+	}
+	if err := ca.Save(certFile, keyFile); err != nil {
+		return nil, fmt.Errorf("saving CA: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Created CA certificate %s\n", certFile)
+	return ca, nil
+}
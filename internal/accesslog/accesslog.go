@@ -0,0 +1,238 @@
+// Package accesslog reads web server access logs into requests that can be
+// replayed against another host.
+//
+// Two formats are understood, detected line by line: the nginx/Apache
+// combined (and common) log format, and JSON lines with "time", "method"
+// and "path" fields.
+package accesslog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EsteveSegura/BrickHauler/internal/config"
+)
+
+// combined matches the common log format, optionally followed by the
+// referer and user agent of the combined format.
+var combined = regexp.MustCompile(`^\S+ \S+ \S+ \[([^\]]+)\] "([^"]*)" \d{3} \S+(?: "([^"]*)" "([^"]*)")?`)
+
+const combinedTime = "02/Jan/2006:15:04:05 -0700"
+
+// Log is the result of reading an access log.
+type Log struct {
+	Requests []config.ReplayRequest
+	// Skipped counts lines that are not a replayable request, such as
+	// malformed requests logged by the server.
+	Skipped int
+}
+
+// entry is a request read from one log line.
+type entry struct {
+	time    time.Time
+	method  string
+	path    string
+	headers http.Header
+	body    string
+}
+
+// Parse reads an access log and builds requests for base's host, ordered
+// by time with offsets relative to the first one.
+func Parse(r io.Reader, base config.URI) (*Log, error) {
+	var (
+		entries []entry
+		log     Log
+	)
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+
+		var (
+			e  entry
+			ok bool
+		)
+		if strings.HasPrefix(line, "{") {
+			e, ok = parseJSON(line)
+		} else {
+			e, ok = parseCombined(line)
+		}
+		if !ok {
+			log.Skipped++
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("reading access log: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no requests found in access log")
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].time.Before(entries[j].time)
+	})
+
+	first := entries[0].time
+	for _, e := range entries {
+		t, err := e.target(base)
+		if err != nil {
+			log.Skipped++
+			continue
+		}
+		log.Requests = append(log.Requests, config.ReplayRequest{
+			Offset: e.time.Sub(first),
+			Target: t,
+		})
+	}
+	if len(log.Requests) == 0 {
+		return nil, fmt.Errorf("no replayable requests found in access log")
+	}
+	return &log, nil
+}
+
+func (e entry) target(base config.URI) (config.Target, error) {
+	method, err := config.ParseHTTPMethod(e.method)
+	if err != nil {
+		return config.Target{}, err
+	}
+	uri, err := base.WithPath(e.path)
+	if err != nil {
+		return config.Target{}, err
+	}
+
+	t := config.Target{
+		Name:    method.String() + " " + e.path,
+		Method:  method,
+		URI:     uri,
+		Headers: e.headers,
+		Weight:  1,
+	}
+	if e.body != "" {
+		t.Body = []byte(e.body)
+	}
+	return t, nil
+}
+
+func parseCombined(line string) (entry, bool) {
+	m := combined.FindStringSubmatch(line)
+	if m == nil {
+		return entry{}, false
+	}
+
+	ts, err := time.Parse(combinedTime, m[1])
+	if err != nil {
+		return entry{}, false
+	}
+
+	// The request line is "METHOD /path HTTP/1.1"; anything else is a
+	// malformed request the server rejected.
+	fields := strings.Fields(m[2])
+	if len(fields) != 3 {
+		return entry{}, false
+	}
+
+	e := entry{time: ts, method: fields[0], path: fields[1]}
+	for name, value := range map[string]string{"Referer": m[3], "User-Agent": m[4]} {
+		if value != "" && value != "-" {
+			if e.headers == nil {
+				e.headers = make(http.Header)
+			}
+			e.headers.Set(name, value)
+		}
+	}
+	return e, true
+}
+
+// jsonLine is one JSON log record. The alternative field names cover the
+// usual structured logging setups.
+type jsonLine struct {
+	Time      json.RawMessage   `json:"time"`
+	Timestamp json.RawMessage   `json:"timestamp"`
+	Method    string            `json:"method"`
+	Path      string            `json:"path"`
+	URI       string            `json:"uri"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers"`
+	Body      string            `json:"body"`
+}
+
+func parseJSON(line string) (entry, bool) {
+	var l jsonLine
+	if err := json.Unmarshal([]byte(line), &l); err != nil {
+		return entry{}, false
+	}
+
+	raw := l.Time
+	if raw == nil {
+		raw = l.Timestamp
+	}
+	ts, ok := parseTime(raw)
+	if !ok {
+		return entry{}, false
+	}
+
+	path := firstNonEmpty(l.Path, l.URI, l.URL)
+	if u, err := url.Parse(path); err == nil && u.IsAbs() {
+		path = u.RequestURI()
+	}
+	if l.Method == "" || path == "" {
+		return entry{}, false
+	}
+
+	e := entry{time: ts, method: l.Method, path: path, body: l.Body}
+	for name, value := range l.Headers {
+		if e.headers == nil {
+			e.headers = make(http.Header)
+		}
+		e.headers.Set(name, value)
+	}
+	return e, true
+}
+
+// parseTime accepts RFC 3339 strings and Unix timestamps in seconds.
+func parseTime(raw json.RawMessage) (time.Time, bool) {
+	if raw == nil {
+		return time.Time{}, false
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		if ts, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return ts, true
+		}
+		if ts, err := time.Parse(combinedTime, s); err == nil {
+			return ts, true
+		}
+		return time.Time{}, false
+	}
+
+	secs, err := strconv.ParseFloat(string(raw), 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, int64(secs*float64(time.Second))), true
+}
+
+func firstNonEmpty(ss ...string) string {
+	for _, s := range ss {
+		if s != "" {
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,90 @@
+package accesslog
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EsteveSegura/BrickHauler/internal/config"
+)
+
+func mustURI(t *testing.T, s string) config.URI {
+	t.Helper()
+	u, err := config.NewURI(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestParse_Combined(t *testing.T) {
+	input := `10.0.0.1 - - [01/May/2024:10:00:02 +0000] "POST /api/cart?id=1 HTTP/1.1" 201 12 "https://shop.test/" "Mozilla/5.0"
+10.0.0.2 - alice [01/May/2024:10:00:00 +0000] "GET / HTTP/2.0" 200 512 "-" "curl/8.0"
+10.0.0.3 - - [01/May/2024:10:00:01 +0000] "\x16\x03\x01" 400 0 "-" "-"
+10.0.0.4 - - [01/May/2024:10:00:03 +0000] "GET /health HTTP/1.0" 200 2
+`
+	log, err := Parse(strings.NewReader(input), mustURI(t, "https://staging.test/ignored"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if log.Skipped != 1 {
+		t.Errorf("Skipped = %d, want 1", log.Skipped)
+	}
+	if len(log.Requests) != 3 {
+		t.Fatalf("got %d requests, want 3", len(log.Requests))
+	}
+
+	tests := []struct {
+		offset time.Duration
+		method string
+		url    string
+	}{
+		{0, "GET", "https://staging.test/"},
+		{2 * time.Second, "POST", "https://staging.test/api/cart?id=1"},
+		{3 * time.Second, "GET", "https://staging.test/health"},
+	}
+	for i, tt := range tests {
+		got := log.Requests[i]
+		if got.Offset != tt.offset || got.Target.Method.String() != tt.method || got.Target.URI.RequestURL() != tt.url {
+			t.Errorf("request %d = %v %s %s, want %v %s %s", i,
+				got.Offset, got.Target.Method, got.Target.URI.RequestURL(), tt.offset, tt.method, tt.url)
+		}
+	}
+
+	first := log.Requests[0].Target.Headers
+	if first.Get("User-Agent") != "curl/8.0" || first.Get("Referer") != "" {
+		t.Errorf("headers = %v", first)
+	}
+}
+
+func TestParse_JSONLines(t *testing.T) {
+	input := `{"time": "2024-05-01T10:00:00.500Z", "method": "PUT", "uri": "/items/1", "body": "{}", "headers": {"content-type": "application/json"}}
+{"timestamp": 1714557600, "method": "GET", "url": "https://prod.test/items?page=2"}
+{"time": "yesterday", "method": "GET", "path": "/"}
+not json at all
+`
+	log, err := Parse(strings.NewReader(input), mustURI(t, "unix:///run/app.sock"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if log.Skipped != 2 || len(log.Requests) != 2 {
+		t.Fatalf("Skipped = %d, requests = %d; want 2 and 2", log.Skipped, len(log.Requests))
+	}
+
+	get, put := log.Requests[0], log.Requests[1]
+	if get.Target.URI.SocketPath() != "/run/app.sock" || get.Target.URI.RequestURL() != "http://localhost/items?page=2" {
+		t.Errorf("GET target = %s via %s", get.Target.URI.RequestURL(), get.Target.URI.SocketPath())
+	}
+	if put.Offset != 500*time.Millisecond || string(put.Target.Body) != "{}" {
+		t.Errorf("PUT offset = %v, body = %q", put.Offset, put.Target.Body)
+	}
+	if put.Target.Headers.Get("Content-Type") != "application/json" {
+		t.Errorf("PUT headers = %v", put.Target.Headers)
+	}
+}
+
+func TestParse_Empty(t *testing.T) {
+	if _, err := Parse(strings.NewReader("garbage\n"), mustURI(t, "http://a.test")); err == nil {
+		t.Error("expected error for a log without requests")
+	}
+}
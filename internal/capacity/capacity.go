@@ -0,0 +1,168 @@
+// Package capacity searches for the highest load a target sustains while
+// meeting its latency and error objectives.
+package capacity
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/EsteveSegura/BrickHauler/internal/metrics"
+)
+
+// SLO is the service level objective every step must meet.
+type SLO struct {
+	// P95 and P99 are the highest accepted latency percentiles. Zero
+	// disables the check.
+	P95 time.Duration
+	P99 time.Duration
+	// MaxErrorRate is the highest accepted share of failed requests,
+	// between 0 and 1. Zero accepts no failures at all.
+	MaxErrorRate float64
+}
+
+// Step is the outcome of running at one load level.
+type Step struct {
+	Level     int
+	RPS       float64
+	P95       time.Duration
+	P99       time.Duration
+	ErrorRate float64
+	Pass      bool
+	// Reason explains why a step failed.
+	Reason string
+}
+
+// Check evaluates a run at level against the objective.
+func (s SLO) Check(level int, snap metrics.Snapshot, elapsed time.Duration) Step {
+	step := Step{Level: level}
+
+	total := snap.TotalRequests()
+	if total == 0 {
+		step.Reason = "no requests completed"
+		return step
+	}
+	step.RPS = float64(total) / elapsed.Seconds()
+	step.ErrorRate = float64(snap.FailureCount) / float64(total)
+	step.P95 = snap.Percentile(95)
+	step.P99 = snap.Percentile(99)
+
+	switch {
+	case step.ErrorRate > s.MaxErrorRate:
+		step.Reason = fmt.Sprintf("error rate %.2f%% > %.2f%%", step.ErrorRate*100, s.MaxErrorRate*100)
+	case s.P95 > 0 && step.P95 > s.P95:
+		step.Reason = fmt.Sprintf("p95 %v > %v", step.P95.Round(time.Millisecond), s.P95)
+	case s.P99 > 0 && step.P99 > s.P99:
+		step.Reason = fmt.Sprintf("p99 %v > %v", step.P99.Round(time.Millisecond), s.P99)
+	default:
+		step.Pass = true
+	}
+	return step
+}
+
+// Probe runs the load at level and returns its metrics and duration.
+type Probe func(ctx context.Context, level int) (metrics.Snapshot, time.Duration, error)
+
+// Search steps the load up from Start by Step until a level fails its
+// objective or Max is reached, then binary searches between the last
+// passing and the first failing level.
+type Search struct {
+	Start int
+	Step  int
+	Max   int
+	// Precision ends the binary search once the passing and failing
+	// levels are this close. Zero means 1.
+	Precision int
+	SLO       SLO
+	Probe     Probe
+	// OnStep, when set, is called after every step.
+	OnStep func(Step)
+}
+
+// Result is the outcome of a search.
+type Result struct {
+	Steps []Step
+	// Best is the highest passing step, nil when every step failed.
+	Best *Step
+	// Knee is the lowest failing level: the load where the objective
+	// breaks. Zero when Max still passed.
+	Knee int
+}
+
+// Validate checks the search parameters.
+func (s Search) Validate() error {
+	if s.Start <= 0 {
+		return fmt.Errorf("start must be greater than 0, got %d", s.Start)
+	}
+	if s.Step <= 0 {
+		return fmt.Errorf("step must be greater than 0, got %d", s.Step)
+	}
+	if s.Max < s.Start {
+		return fmt.Errorf("max (%d) must be at least start (%d)", s.Max, s.Start)
+	}
+	if s.Precision < 0 {
+		return fmt.Errorf("precision cannot be negative, got %d", s.Precision)
+	}
+	if s.SLO.MaxErrorRate < 0 || s.SLO.MaxErrorRate > 1 {
+		return fmt.Errorf("max error rate must be between 0 and 1, got %g", s.SLO.MaxErrorRate)
+	}
+	return nil
+}
+
+// Run performs the search. When ctx is cancelled it returns the steps run
+// so far along with the error.
+func (s Search) Run(ctx context.Context) (*Result, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	precision := max(s.Precision, 1)
+
+	res := &Result{}
+	var best Step
+	passed, failed := 0, 0
+
+	probe := func(level int) error {
+		snap, elapsed, err := s.Probe(ctx, level)
+		if err != nil {
+			return err
+		}
+		step := s.SLO.Check(level, snap, elapsed)
+		res.Steps = append(res.Steps, step)
+		if s.OnStep != nil {
+			s.OnStep(step)
+		}
+		if step.Pass {
+			passed, best = level, step
+		} else {
+			failed = level
+		}
+		return nil
+	}
+
+	// Ramp up until the objective breaks.
+	for level := s.Start; failed == 0; level = min(level+s.Step, s.Max) {
+		if err := probe(level); err != nil {
+			return res.finish(best, failed), err
+		}
+		if level == s.Max {
+			break
+		}
+	}
+
+	// Narrow down between the last passing and the first failing level.
+	for failed > 0 && failed-passed > precision {
+		if err := probe(passed + (failed-passed)/2); err != nil {
+			return res.finish(best, failed), err
+		}
+	}
+
+	return res.finish(best, failed), nil
+}
+
+func (r *Result) finish(best Step, knee int) *Result {
+	if best.Pass {
+		r.Best = &best
+	}
+	r.Knee = knee
+	return r
+}
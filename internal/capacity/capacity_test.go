@@ -0,0 +1,146 @@
+package capacity
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/EsteveSegura/BrickHauler/internal/metrics"
+)
+
+// fakeTarget passes its objective up to limit and degrades beyond it.
+func fakeTarget(limit int) Probe {
+	return func(_ context.Context, level int) (metrics.Snapshot, time.Duration, error) {
+		m := metrics.New(0)
+		latency := 50 * time.Millisecond
+		if level > limit {
+			latency = time.Second
+		}
+		for i := 0; i < level; i++ {
+			m.RecordSuccess(latency)
+		}
+		return m.Snapshot(), time.Second, nil
+	}
+}
+
+func levels(steps []Step) []int {
+	var out []int
+	for _, s := range steps {
+		out = append(out, s.Level)
+	}
+	return out
+}
+
+func TestSearch_RampThenBisect(t *testing.T) {
+	var seen []int
+	s := Search{
+		Start: 10, Step: 10, Max: 100,
+		SLO:    SLO{P95: 200 * time.Millisecond},
+		Probe:  fakeTarget(37),
+		OnStep: func(st Step) { seen = append(seen, st.Level) },
+	}
+
+	res, err := s.Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []int{10, 20, 30, 40, 35, 37, 38}
+	if got := levels(res.Steps); !slices.Equal(got, want) || !slices.Equal(seen, want) {
+		t.Fatalf("levels = %v, reported %v; want %v", got, seen, want)
+	}
+
+	if res.Best == nil || res.Best.Level != 37 || res.Knee != 38 {
+		t.Errorf("best = %+v, knee = %d; want 37 and 38", res.Best, res.Knee)
+	}
+	if res.Best.RPS != 37 {
+		t.Errorf("best RPS = %v, want 37", res.Best.RPS)
+	}
+}
+
+func TestSearch_Precision(t *testing.T) {
+	s := Search{Start: 100, Step: 100, Max: 1000, Precision: 25, SLO: SLO{P95: 200 * time.Millisecond}, Probe: fakeTarget(430)}
+	res, err := s.Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Knee-res.Best.Level > 25 || res.Best.Level > 430 || res.Knee <= 430 {
+		t.Errorf("best = %d, knee = %d; want a pass and fail within 25 around 430", res.Best.Level, res.Knee)
+	}
+}
+
+func TestSearch_MaxPasses(t *testing.T) {
+	s := Search{Start: 5, Step: 10, Max: 20, Probe: fakeTarget(1000)}
+	res, err := s.Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := levels(res.Steps); !slices.Equal(got, []int{5, 15, 20}) {
+		t.Errorf("levels = %v, want 5, 15, 20", got)
+	}
+	if res.Best.Level != 20 || res.Knee != 0 {
+		t.Errorf("best = %d, knee = %d; want 20 and none", res.Best.Level, res.Knee)
+	}
+}
+
+func TestSearch_ProbeError(t *testing.T) {
+	calls := 0
+	s := Search{Start: 1, Step: 1, Max: 10, Probe: func(ctx context.Context, level int) (metrics.Snapshot, time.Duration, error) {
+		calls++
+		if calls == 3 {
+			return metrics.Snapshot{}, 0, context.Canceled
+		}
+		return fakeTarget(100)(ctx, level)
+	}}
+	res, err := s.Run(context.Background())
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if res.Best == nil || res.Best.Level != 2 {
+		t.Errorf("partial best = %+v, want level 2", res.Best)
+	}
+}
+
+func TestSLO_Check(t *testing.T) {
+	m := metrics.New(0)
+	for i := 0; i < 98; i++ {
+		m.RecordSuccess(10 * time.Millisecond)
+	}
+	m.RecordFailure()
+	m.RecordFailure()
+
+	slo := SLO{MaxErrorRate: 0.01}
+	if step := slo.Check(1, m.Snapshot(), time.Second); step.Pass || step.Reason == "" {
+		t.Errorf("2%% errors passed a 1%% objective: %+v", step)
+	}
+
+	slo.MaxErrorRate = 0.05
+	if step := slo.Check(1, m.Snapshot(), time.Second); !step.Pass || step.RPS != 100 {
+		t.Errorf("step = %+v, want pass at 100 rps", step)
+	}
+
+	if step := slo.Check(1, metrics.Snapshot{}, time.Second); step.Pass {
+		t.Error("a step without requests should fail")
+	}
+
+	// A zero error rate accepts no failures rather than disabling the check.
+	slo.MaxErrorRate = 0
+	if step := slo.Check(1, m.Snapshot(), time.Second); step.Pass {
+		t.Errorf("failures passed a zero error rate objective: %+v", step)
+	}
+}
+
+func TestSearch_Validate(t *testing.T) {
+	for _, s := range []Search{
+		{Start: 0, Step: 1, Max: 1},
+		{Start: 1, Step: 0, Max: 1},
+		{Start: 10, Step: 1, Max: 5},
+		{Start: 1, Step: 1, Max: 1, SLO: SLO{MaxErrorRate: 2}},
+	} {
+		if err := s.Validate(); err == nil {
+			t.Errorf("Validate(%+v) expected error", s)
+		}
+	}
+}
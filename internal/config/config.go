@@ -1,9 +1,12 @@
 package config
 
 import (
+	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
+	"time"
 )
 
 // Config holds all configuration for a load test run.
@@ -13,8 +16,59 @@ type Config struct {
 	Concurrency int
 	Requests    int
 	Cookies     []*http.Cookie
-	ProxyURL    *url.URL
+	Headers     http.Header
+	Body        []byte
+	Proxies     []*url.URL
 	LiveFeed    bool
+	Transport   Transport
+	TLSConfig   *tls.Config
+	HTTPVersion HTTPVersion
+	HTTP2       HTTP2
+	// IsolateConnections gives every virtual user its own HTTP client,
+	// like N independent browsers.
+	IsolateConnections bool
+	// Resolve pins "host:port" pairs to IP addresses, like curl --resolve.
+	Resolve map[string][]string
+	// UnixSocket sends every request over this socket, using the URI only
+	// for the Host header and path.
+	UnixSocket string
+	// ProxyRotation assigns proxies to requests when there are several.
+	ProxyRotation ProxyRotation
+	// SourceIPs binds outgoing connections to these local addresses,
+	// spreading virtual users across them.
+	SourceIPs []net.IP
+	// Targets replaces URI, Method and Body with several weighted request
+	// templates. Headers and Cookies still apply to all of them.
+	Targets []Target
+	// Sequence makes every virtual user walk Targets in order instead of
+	// picking them by weight.
+	Sequence bool
+	// Replay sends recorded requests to the URI's host on their original
+	// schedule instead of running Requests through the targets. Requests
+	// is the number of recorded requests.
+	Replay *Replay
+	// ThinkTime is the pause after each request, for targets that do not
+	// set their own.
+	ThinkTime ThinkTime
+	// Pacing starts each iteration of a virtual user this long after the
+	// previous one, however long its requests took. An iteration is one
+	// request, or one pass over the targets in sequence mode.
+	Pacing time.Duration
+	// MaxRPS caps the requests per second of the whole run, across all
+	// virtual users. Zero means no limit.
+	MaxRPS float64
+	// Duration stops the run after this long. With Requests also set, the
+	// run ends at whichever limit is reached first; with Requests zero,
+	// virtual users keep sending until the time is up.
+	Duration time.Duration
+	// Warmup sends traffic that is not measured before the run starts.
+	Warmup Warmup
+	// Profile varies the number of active virtual users over the run, up
+	// to Concurrency. Requests is then a budget shared by all of them.
+	Profile *Profile
+	// Adjustable lets the virtual users and the rate limit change while
+	// the test runs. Requests is then a budget shared by all users.
+	Adjustable bool
 }
 
 // Validate checks all configuration values.
@@ -23,25 +77,178 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("concurrency must be greater than 0, got %d", c.Concurrency)
 	}
 
-	if c.Requests <= 0 {
+	if c.Duration < 0 {
+		return fmt.Errorf("duration cannot be negative, got %v", c.Duration)
+	}
+
+	if c.Requests < 0 || (c.Requests == 0 && c.Duration == 0) {
 		return fmt.Errorf("requests must be greater than 0, got %d", c.Requests)
 	}
 
-	if c.Requests%c.Concurrency != 0 {
+	switch {
+	case c.Replay != nil:
+		if err := c.validateReplay(); err != nil {
+			return err
+		}
+	case c.Profile != nil:
+		if err := c.validateProfile(); err != nil {
+			return err
+		}
+	case c.Adjustable:
+		// Users share the budget, so any number of requests works.
+	case c.Requests%c.Concurrency != 0:
 		return fmt.Errorf(
 			"requests (%d) must be evenly divisible by concurrency (%d)",
 			c.Requests, c.Concurrency,
 		)
 	}
 
-	if !c.Method.IsValid() {
-		return fmt.Errorf("invalid HTTP method: %s", c.Method)
+	if err := c.validateTargets(); err != nil {
+		return err
+	}
+
+	if len(c.Proxies) > 1 {
+		if _, err := ParseProxyRotation(string(c.ProxyRotation)); err != nil {
+			return err
+		}
+	}
+
+	if c.MaxRPS < 0 {
+		return fmt.Errorf("max RPS cannot be negative, got %g", c.MaxRPS)
+	}
+	if c.Pacing < 0 {
+		return fmt.Errorf("pacing cannot be negative, got %v", c.Pacing)
+	}
+	if c.Replay != nil && (!c.ThinkTime.IsZero() || c.Pacing > 0 || !c.Warmup.IsZero()) {
+		return fmt.Errorf("think time, pacing and warm-up cannot be used with replay, which keeps the recorded timing")
+	}
+
+	if err := c.validateHTTPVersion(); err != nil {
+		return err
+	}
+
+	if err := c.Transport.Validate(); err != nil {
+		return err
 	}
 
 	return nil
 }
 
-// RequestsPerWorker returns how many requests each worker should make.
+// validateProfile checks that there are enough virtual users for the
+// profile and that it is not combined with a replay.
+func (c *Config) validateProfile() error {
+	if err := c.Profile.Validate(); err != nil {
+		return err
+	}
+	if peak := c.Profile.MaxUsers(); c.Concurrency < peak {
+		return fmt.Errorf("concurrency (%d) must be at least the profile's peak of %d users", c.Concurrency, peak)
+	}
+	return nil
+}
+
+// RequestsPerWorker returns how many requests each worker should make, or
+// zero when the run is only limited by its duration.
 func (c *Config) RequestsPerWorker() int {
 	return c.Requests / c.Concurrency
 }
+
+// TargetList returns the request templates for the run: the configured
+// targets, or a single target built from URI, Method and Body.
+func (c *Config) TargetList() []Target {
+	if len(c.Targets) > 0 {
+		return c.Targets
+	}
+	return []Target{{
+		Name:   c.URI.String(),
+		Method: c.Method,
+		URI:    c.URI,
+		Body:   c.Body,
+		Weight: 1,
+	}}
+}
+
+// validateTargets checks every target and that they can share one client.
+func (c *Config) validateTargets() error {
+	if len(c.Targets) == 0 {
+		if !c.Method.IsValid() {
+			return fmt.Errorf("invalid HTTP method: %s", c.Method)
+		}
+		return nil
+	}
+
+	totalWeight := 0
+	for _, t := range c.Targets {
+		if err := t.Validate(); err != nil {
+			return err
+		}
+		if t.URI.SocketPath() != c.Targets[0].URI.SocketPath() {
+			return fmt.Errorf("all targets must use the same unix socket")
+		}
+		totalWeight += t.Weight
+	}
+	if totalWeight == 0 {
+		return fmt.Errorf("at least one target must have a positive weight")
+	}
+	return nil
+}
+
+// validateReplay checks the replay and that it does not mix with targets.
+func (c *Config) validateReplay() error {
+	if len(c.Targets) > 0 {
+		return fmt.Errorf("replay cannot be combined with targets")
+	}
+	if c.Profile != nil || c.Adjustable {
+		return fmt.Errorf("replay cannot be combined with a load profile or adjustable load")
+	}
+	if c.Requests != len(c.Replay.Requests) {
+		return fmt.Errorf("requests (%d) must match the replayed requests (%d)", c.Requests, len(c.Replay.Requests))
+	}
+	return c.Replay.Validate()
+}
+
+// validateHTTPVersion checks the protocol can be spoken over the URI scheme.
+func (c *Config) validateHTTPVersion() error {
+	for _, t := range c.TargetList() {
+		scheme := ""
+		if u := t.URI.URL(); u != nil {
+			scheme = u.Scheme
+		}
+
+		switch {
+		case c.HTTPVersion == HTTPVersionH2C && scheme == "https":
+			return fmt.Errorf("h2c is cleartext HTTP/2 and requires an http:// URI")
+		case c.HTTPVersion == HTTPVersion2 && scheme == "http":
+			return fmt.Errorf("HTTP/2 over http:// requires --http-version h2c")
+		}
+	}
+	return nil
+}
+
+// SocketPath returns the unix socket requests are sent over, if any.
+func (c *Config) SocketPath() string {
+	if c.UnixSocket != "" {
+		return c.UnixSocket
+	}
+	return c.TargetList()[0].URI.SocketPath()
+}
+
+// PoolSize returns the connection pool size for the HTTP client. Unless set
+// explicitly it matches concurrency, so the pool never caps the virtual users.
+// Adjustable runs can add users as they go, so their pool is unlimited.
+func (c *Config) PoolSize() int {
+	if c.Transport.MaxConnsPerHost > 0 {
+		return c.Transport.MaxConnsPerHost
+	}
+	if c.Adjustable {
+		return 0
+	}
+	return c.Concurrency
+}
+
+// IdlePoolSize returns the number of idle connections kept for reuse.
+func (c *Config) IdlePoolSize() int {
+	if c.Transport.MaxIdleConns > 0 {
+		return c.Transport.MaxIdleConns
+	}
+	return c.Concurrency
+}
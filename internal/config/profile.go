@@ -0,0 +1,301 @@
+package config
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProfileShape is the shape of a load profile.
+type ProfileShape string
+
+const (
+	ProfileSpike ProfileShape = "spike"
+	ProfileStep  ProfileShape = "step"
+	ProfileSine  ProfileShape = "sine"
+	ProfileCSV   ProfileShape = "csv"
+)
+
+// ProfilePoint is the number of virtual users wanted at a point of a
+// custom profile.
+type ProfilePoint struct {
+	At    time.Duration
+	Users int
+}
+
+// Profile varies the number of active virtual users over the run, to
+// rehearse traffic that a constant concurrency cannot express.
+type Profile struct {
+	Shape ProfileShape
+	// Base is the users before and after a spike, at the start of a step
+	// profile and at the trough of a sine wave.
+	Base int
+	// Peak is the users during a spike, the most a step profile reaches
+	// and the crest of a sine wave.
+	Peak int
+	// Increment is the users a step profile adds at every step.
+	Increment int
+	// Start is when a spike begins.
+	Start time.Duration
+	// Length is how long a spike lasts, how often a step profile steps up
+	// and the period of a sine wave.
+	Length time.Duration
+	// Points are the users over time of a custom profile, in order.
+	Points []ProfilePoint
+}
+
+// ParseProfile parses a load profile specification:
+//
+//	spike:10,200,1m,30s   10 users, 200 from 1m for 30s, then 10 again
+//	step:10,10,30s,100    10 users, 10 more every 30s up to 100
+//	sine:10,100,2m        between 10 and 100 users, with a 2m period
+//	csv:profile.csv       time,users rows, interpolated linearly
+//
+// An empty string means no profile.
+func ParseProfile(s string) (*Profile, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	kind, args, found := strings.Cut(s, ":")
+	if !found || args == "" {
+		return nil, fmt.Errorf("invalid profile %q: must be spike, step, sine or csv followed by its parameters", s)
+	}
+
+	shape := ProfileShape(strings.ToLower(kind))
+	values := strings.Split(args, ",")
+	p := &Profile{Shape: shape}
+	var err error
+	switch shape {
+	case ProfileCSV:
+		if p.Points, err = LoadProfileCSV(args); err != nil {
+			return nil, err
+		}
+	case ProfileSpike:
+		if len(values) != 4 {
+			return nil, fmt.Errorf("invalid profile %q: spike takes baseline,peak,start,length", s)
+		}
+		err = parseProfileValues(values, &p.Base, &p.Peak, &p.Start, &p.Length)
+	case ProfileStep:
+		if len(values) != 4 {
+			return nil, fmt.Errorf("invalid profile %q: step takes start,increment,interval,max", s)
+		}
+		err = parseProfileValues(values, &p.Base, &p.Increment, &p.Length, &p.Peak)
+	case ProfileSine:
+		if len(values) != 3 {
+			return nil, fmt.Errorf("invalid profile %q: sine takes min,max,period", s)
+		}
+		err = parseProfileValues(values, &p.Base, &p.Peak, &p.Length)
+	default:
+		return nil, fmt.Errorf("invalid profile %q: shape must be spike, step, sine or csv", s)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid profile %q: %w", s, err)
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid profile %q: %w", s, err)
+	}
+	return p, nil
+}
+
+// parseProfileValues parses each value into the matching destination, an
+// *int for users or a *time.Duration.
+func parseProfileValues(values []string, dst ...any) error {
+	for i, v := range values {
+		v = strings.TrimSpace(v)
+		switch d := dst[i].(type) {
+		case *int:
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("%q is not a number of users", v)
+			}
+			*d = n
+		case *time.Duration:
+			dur, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("%q is not a duration", v)
+			}
+			*d = dur
+		}
+	}
+	return nil
+}
+
+// LoadProfileCSV reads a custom profile from a CSV file of time,users rows.
+// Times are durations such as 90s or plain seconds. A header row and lines
+// starting with # are skipped.
+func LoadProfileCSV(path string) ([]ProfilePoint, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading profile: %w", err)
+	}
+	defer f.Close()
+
+	points, err := parseProfileCSV(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading profile %s: %w", path, err)
+	}
+	return points, nil
+}
+
+func parseProfileCSV(r io.Reader) ([]ProfilePoint, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = 2
+	cr.TrimLeadingSpace = true
+
+	var points []ProfilePoint
+	for line := 1; ; line++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		at, err := parseProfileTime(record[0])
+		if err != nil {
+			if line == 1 {
+				continue // header
+			}
+			return nil, fmt.Errorf("line %d: %q is not a time", line, record[0])
+		}
+		users, err := strconv.Atoi(strings.TrimSpace(record[1]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %q is not a number of users", line, record[1])
+		}
+		if users < 0 {
+			return nil, fmt.Errorf("line %d: users cannot be negative", line)
+		}
+		if len(points) > 0 && at <= points[len(points)-1].At {
+			return nil, fmt.Errorf("line %d: times must increase", line)
+		}
+		points = append(points, ProfilePoint{At: at, Users: users})
+	}
+
+	if len(points) == 0 {
+		return nil, fmt.Errorf("no time,users rows")
+	}
+	return points, nil
+}
+
+func parseProfileTime(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+	return time.ParseDuration(s)
+}
+
+// Validate checks that the profile is well formed.
+func (p *Profile) Validate() error {
+	if p.Base < 0 || p.Increment < 0 || p.Start < 0 {
+		return fmt.Errorf("values cannot be negative")
+	}
+	switch p.Shape {
+	case ProfileSpike, ProfileSine:
+		if p.Peak <= p.Base {
+			return fmt.Errorf("peak (%d) must be greater than the baseline (%d)", p.Peak, p.Base)
+		}
+		if p.Length <= 0 {
+			return fmt.Errorf("length must be greater than 0")
+		}
+	case ProfileStep:
+		if p.Increment == 0 || p.Length <= 0 {
+			return fmt.Errorf("increment and interval must be greater than 0")
+		}
+		if p.Peak < p.Base {
+			return fmt.Errorf("max (%d) must be at least the start (%d)", p.Peak, p.Base)
+		}
+	}
+	if p.MaxUsers() == 0 {
+		return fmt.Errorf("profile never has any users")
+	}
+	return nil
+}
+
+// Users returns how many virtual users should be active at elapsed.
+func (p *Profile) Users(elapsed time.Duration) int {
+	switch p.Shape {
+	case ProfileSpike:
+		if elapsed >= p.Start && elapsed < p.Start+p.Length {
+			return p.Peak
+		}
+		return p.Base
+	case ProfileStep:
+		steps := int(elapsed / p.Length)
+		return min(p.Base+steps*p.Increment, p.Peak)
+	case ProfileSine:
+		phase := 2 * math.Pi * float64(elapsed) / float64(p.Length)
+		wave := (1 - math.Cos(phase)) / 2
+		return p.Base + int(math.Round(wave*float64(p.Peak-p.Base)))
+	case ProfileCSV:
+		return p.interpolate(elapsed)
+	}
+	return 0
+}
+
+// interpolate returns the users at elapsed on the line between the custom
+// points around it, holding the first and last values outside them.
+func (p *Profile) interpolate(elapsed time.Duration) int {
+	if elapsed <= p.Points[0].At {
+		return p.Points[0].Users
+	}
+	for i := 1; i < len(p.Points); i++ {
+		prev, next := p.Points[i-1], p.Points[i]
+		if elapsed < next.At {
+			share := float64(elapsed-prev.At) / float64(next.At-prev.At)
+			return prev.Users + int(math.Round(share*float64(next.Users-prev.Users)))
+		}
+	}
+	return p.Points[len(p.Points)-1].Users
+}
+
+// Finished reports whether the profile asks for no users at elapsed and
+// will not ask for any again.
+func (p *Profile) Finished(elapsed time.Duration) bool {
+	switch p.Shape {
+	case ProfileSpike:
+		return p.Base == 0 && elapsed >= p.Start+p.Length
+	case ProfileCSV:
+		last := p.Points[len(p.Points)-1]
+		return last.Users == 0 && elapsed >= last.At
+	}
+	return false
+}
+
+// MaxUsers returns the most virtual users the profile asks for at once.
+func (p *Profile) MaxUsers() int {
+	if p.Shape != ProfileCSV {
+		return p.Peak
+	}
+	most := 0
+	for _, pt := range p.Points {
+		most = max(most, pt.Users)
+	}
+	return most
+}
+
+// String describes the profile for the results header.
+func (p *Profile) String() string {
+	switch p.Shape {
+	case ProfileSpike:
+		return fmt.Sprintf("spike from %d to %d users at %v for %v", p.Base, p.Peak, p.Start, p.Length)
+	case ProfileStep:
+		return fmt.Sprintf("step from %d users, %d more every %v up to %d", p.Base, p.Increment, p.Length, p.Peak)
+	case ProfileSine:
+		return fmt.Sprintf("sine between %d and %d users every %v", p.Base, p.Peak, p.Length)
+	case ProfileCSV:
+		last := p.Points[len(p.Points)-1]
+		return fmt.Sprintf("custom, %d points over %v", len(p.Points), last.At)
+	}
+	return string(p.Shape)
+}
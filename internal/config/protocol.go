@@ -0,0 +1,45 @@
+package config
+
+import "fmt"
+
+// HTTPVersion selects the HTTP protocol used to talk to the target.
+type HTTPVersion string
+
+const (
+	// HTTPVersionAuto negotiates HTTP/2 over TLS and falls back to HTTP/1.1.
+	HTTPVersionAuto HTTPVersion = ""
+	HTTPVersion1    HTTPVersion = "1.1"
+	HTTPVersion2    HTTPVersion = "2"
+	HTTPVersionH2C  HTTPVersion = "h2c"
+)
+
+// ParseHTTPVersion validates and returns an HTTPVersion.
+func ParseHTTPVersion(s string) (HTTPVersion, error) {
+	switch v := HTTPVersion(s); v {
+	case HTTPVersionAuto, HTTPVersion1, HTTPVersion2, HTTPVersionH2C:
+		return v, nil
+	case "1", "http/1.1":
+		return HTTPVersion1, nil
+	case "2.0", "h2":
+		return HTTPVersion2, nil
+	}
+	return "", fmt.Errorf("invalid HTTP version %q: must be 1.1, 2 or h2c", s)
+}
+
+// String implements the Stringer interface.
+func (v HTTPVersion) String() string {
+	if v == HTTPVersionAuto {
+		return "auto"
+	}
+	return string(v)
+}
+
+// HTTP2 holds HTTP/2 stream multiplexing settings. Zero values mean
+// "use the default".
+type HTTP2 struct {
+	MaxReadFrameSize int
+	// Flow control windows, which bound how much data each stream and
+	// each multiplexed connection may have in flight.
+	MaxReceiveBufferPerStream     int
+	MaxReceiveBufferPerConnection int
+}
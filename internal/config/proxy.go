@@ -0,0 +1,103 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// ProxyRotation selects how proxies from the pool are assigned to requests.
+type ProxyRotation string
+
+const (
+	ProxyRoundRobin ProxyRotation = "round-robin"
+	ProxyRandom     ProxyRotation = "random"
+	// ProxySticky pins each virtual user to one proxy for the whole run.
+	ProxySticky ProxyRotation = "sticky"
+)
+
+// ParseProxyRotation validates and returns a ProxyRotation.
+func ParseProxyRotation(s string) (ProxyRotation, error) {
+	switch r := ProxyRotation(strings.ToLower(s)); r {
+	case ProxyRoundRobin, ProxyRandom, ProxySticky:
+		return r, nil
+	}
+	return "", fmt.Errorf("invalid proxy rotation %q: must be round-robin, random or sticky", s)
+}
+
+var proxySchemes = map[string]bool{
+	"http":    true,
+	"https":   true,
+	"socks5":  true,
+	"socks5h": true,
+}
+
+// ParseProxyURL validates a proxy URL. Supported schemes are http, https,
+// socks5 and socks5h. Credentials may be embedded in the URL or given
+// separately as "user:password" in auth, which only applies to a URL
+// without its own.
+func ParseProxyURL(s, auth string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+
+	if !proxySchemes[strings.ToLower(u.Scheme)] {
+		return nil, fmt.Errorf("invalid proxy URL %q: scheme must be http, https, socks5 or socks5h", s)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q: missing host", s)
+	}
+
+	if auth != "" {
+		user, password, found := strings.Cut(auth, ":")
+		if !found || user == "" {
+			return nil, fmt.Errorf("invalid proxy credentials: must be user:password")
+		}
+		if u.User == nil {
+			u.User = url.UserPassword(user, password)
+		}
+	}
+
+	return u, nil
+}
+
+// LoadProxies reads proxy URLs from a file, one per line. Blank lines and
+// lines starting with # are ignored.
+func LoadProxies(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading proxy file: %w", err)
+	}
+	defer f.Close()
+
+	var proxies []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		proxies = append(proxies, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading proxy file: %w", err)
+	}
+	return proxies, nil
+}
+
+// ParseProxyURLs validates every proxy URL, applying auth to those without
+// credentials of their own.
+func ParseProxyURLs(ss []string, auth string) ([]*url.URL, error) {
+	var proxies []*url.URL
+	for _, s := range ss {
+		u, err := ParseProxyURL(s, auth)
+		if err != nil {
+			return nil, err
+		}
+		proxies = append(proxies, u)
+	}
+	return proxies, nil
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Replay re-issues recorded requests on their original schedule.
+type Replay struct {
+	Requests []ReplayRequest
+	// Speed scales the schedule: 2 replays the traffic twice as fast.
+	Speed float64
+}
+
+// ReplayRequest is a request sent Offset after the start of the replay,
+// before scaling by the speed.
+type ReplayRequest struct {
+	Offset time.Duration
+	Target Target
+}
+
+// Validate checks the replay values.
+func (r *Replay) Validate() error {
+	if len(r.Requests) == 0 {
+		return fmt.Errorf("replay has no requests")
+	}
+	if r.Speed <= 0 {
+		return fmt.Errorf("replay speed must be greater than 0, got %g", r.Speed)
+	}
+	for _, req := range r.Requests {
+		if err := req.Target.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// At returns when req is due, relative to the start of the replay.
+func (r *Replay) At(req ReplayRequest) time.Duration {
+	return time.Duration(float64(req.Offset) / r.Speed)
+}
+
+// ParseReplaySpeed parses a speed multiplier such as "2", "2x" or "0.5x".
+func ParseReplaySpeed(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(strings.ToLower(s), "x"), 64)
+	if err != nil || v <= 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("invalid replay speed %q: must be a positive multiplier such as 2x", s)
+	}
+	return v, nil
+}
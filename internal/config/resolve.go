@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ParseResolve parses a curl style "host:port:addr[,addr...]" mapping and
+// returns the "host:port" key with the addresses it should connect to.
+func ParseResolve(s string) (string, []string, error) {
+	parts := strings.SplitN(s, ":", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		return "", nil, fmt.Errorf("invalid resolve %q: must be host:port:addr[,addr...]", s)
+	}
+
+	host, port := strings.ToLower(parts[0]), parts[1]
+	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		return "", nil, fmt.Errorf("invalid resolve %q: bad port %q", s, port)
+	}
+
+	var addrs []string
+	for _, a := range strings.Split(parts[2], ",") {
+		a = strings.Trim(strings.TrimSpace(a), "[]")
+		if net.ParseIP(a) == nil {
+			return "", nil, fmt.Errorf("invalid resolve %q: %q is not an IP address", s, a)
+		}
+		addrs = append(addrs, a)
+	}
+
+	return net.JoinHostPort(host, port), addrs, nil
+}
+
+// ParseResolves parses multiple resolve mappings into a lookup table.
+// Mappings for the same host and port are merged.
+func ParseResolves(ss []string) (map[string][]string, error) {
+	if len(ss) == 0 {
+		return nil, nil
+	}
+
+	table := make(map[string][]string)
+	for _, s := range ss {
+		key, addrs, err := ParseResolve(s)
+		if err != nil {
+			return nil, err
+		}
+		table[key] = append(table[key], addrs...)
+	}
+	return table, nil
+}
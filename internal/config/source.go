@@ -0,0 +1,19 @@
+package config
+
+import (
+	"fmt"
+	"net"
+)
+
+// ParseSourceIPs validates local addresses to bind outgoing connections to.
+func ParseSourceIPs(ss []string) ([]net.IP, error) {
+	var ips []net.IP
+	for _, s := range ss {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid source IP %q", s)
+		}
+		ips = append(ips, ip)
+	}
+	return ips, nil
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Target is one request template. A run with several targets spreads its
+// requests across them according to their weights.
+type Target struct {
+	Name    string
+	Method  HTTPMethod
+	URI     URI
+	Headers http.Header
+	Body    []byte
+	Cookies []*http.Cookie
+	Weight  int
+	// ThinkTime is how long the virtual user pauses after this request.
+	ThinkTime time.Duration
+}
+
+// Validate checks the target values.
+func (t Target) Validate() error {
+	if !t.Method.IsValid() {
+		return fmt.Errorf("target %q: invalid HTTP method: %s", t.Name, t.Method)
+	}
+	if t.URI.URL() == nil {
+		return fmt.Errorf("target %q: URI is required", t.Name)
+	}
+	if t.Weight < 0 {
+		return fmt.Errorf("target %q: weight cannot be negative, got %d", t.Name, t.Weight)
+	}
+	if t.ThinkTime < 0 {
+		return fmt.Errorf("target %q: think time cannot be negative, got %v", t.Name, t.ThinkTime)
+	}
+	return nil
+}
+
+// ParseHeader parses a "Name: value" string.
+func ParseHeader(s string) (string, string, error) {
+	name, value, found := strings.Cut(s, ":")
+	name = strings.TrimSpace(name)
+	if !found || name == "" {
+		return "", "", fmt.Errorf("invalid header format %q: must be Name: value", s)
+	}
+	return name, strings.TrimSpace(value), nil
+}
+
+// ParseHeaders parses multiple header strings.
+func ParseHeaders(ss []string) (http.Header, error) {
+	headers := make(http.Header)
+	for _, s := range ss {
+		name, value, err := ParseHeader(s)
+		if err != nil {
+			return nil, err
+		}
+		headers.Add(name, value)
+	}
+	return headers, nil
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// Distribution is the shape of the random pauses between requests.
+type Distribution string
+
+const (
+	DistributionFixed       Distribution = "fixed"
+	DistributionUniform     Distribution = "uniform"
+	DistributionNormal      Distribution = "normal"
+	DistributionExponential Distribution = "exponential"
+)
+
+// ThinkTime is how long a virtual user pauses after each request, like a
+// user reading a page before the next click.
+type ThinkTime struct {
+	Distribution Distribution
+	// Mean is the fixed pause, or the mean of the normal and exponential
+	// distributions.
+	Mean time.Duration
+	// StdDev is the standard deviation of the normal distribution.
+	StdDev time.Duration
+	// Min and Max bound the uniform distribution.
+	Min, Max time.Duration
+}
+
+// ParseThinkTime parses a think time specification:
+//
+//	500ms                 fixed
+//	200ms-800ms           uniform between the two values
+//	normal:500ms,100ms    normal with mean and standard deviation
+//	exp:500ms             exponential with mean
+//
+// An empty string means no think time.
+func ParseThinkTime(s string) (ThinkTime, error) {
+	if s == "" {
+		return ThinkTime{}, nil
+	}
+
+	kind, args, found := strings.Cut(s, ":")
+	if !found {
+		if lo, hi, ok := strings.Cut(s, "-"); ok {
+			kind, args = string(DistributionUniform), lo+","+hi
+		} else {
+			kind, args = string(DistributionFixed), s
+		}
+	}
+
+	values, err := parseDurations(args)
+	if err != nil {
+		return ThinkTime{}, fmt.Errorf("invalid think time %q: %w", s, err)
+	}
+
+	var t ThinkTime
+	switch Distribution(strings.ToLower(kind)) {
+	case DistributionFixed:
+		if len(values) != 1 {
+			return ThinkTime{}, fmt.Errorf("invalid think time %q: fixed takes one duration", s)
+		}
+		t = ThinkTime{Distribution: DistributionFixed, Mean: values[0]}
+	case DistributionUniform:
+		if len(values) != 2 || values[0] > values[1] {
+			return ThinkTime{}, fmt.Errorf("invalid think time %q: uniform takes min-max", s)
+		}
+		t = ThinkTime{Distribution: DistributionUniform, Min: values[0], Max: values[1]}
+	case DistributionNormal:
+		if len(values) != 2 {
+			return ThinkTime{}, fmt.Errorf("invalid think time %q: normal takes mean,stddev", s)
+		}
+		t = ThinkTime{Distribution: DistributionNormal, Mean: values[0], StdDev: values[1]}
+	case DistributionExponential, "exp":
+		if len(values) != 1 {
+			return ThinkTime{}, fmt.Errorf("invalid think time %q: exponential takes a mean", s)
+		}
+		t = ThinkTime{Distribution: DistributionExponential, Mean: values[0]}
+	default:
+		return ThinkTime{}, fmt.Errorf("invalid think time %q: distribution must be fixed, uniform, normal or exp", s)
+	}
+	return t, nil
+}
+
+func parseDurations(s string) ([]time.Duration, error) {
+	var out []time.Duration
+	for _, part := range strings.Split(s, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("durations cannot be negative")
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// IsZero reports whether no think time is configured.
+func (t ThinkTime) IsZero() bool {
+	return t.Distribution == ""
+}
+
+// Sample draws a pause from the distribution. Negative draws of the normal
+// distribution are clamped to zero.
+func (t ThinkTime) Sample() time.Duration {
+	var d time.Duration
+	switch t.Distribution {
+	case DistributionFixed:
+		d = t.Mean
+	case DistributionUniform:
+		d = t.Min + time.Duration(rand.Float64()*float64(t.Max-t.Min))
+	case DistributionNormal:
+		d = t.Mean + time.Duration(rand.NormFloat64()*float64(t.StdDev))
+	case DistributionExponential:
+		d = time.Duration(rand.ExpFloat64() * float64(t.Mean))
+	}
+	return max(d, 0)
+}
+
+// String describes the think time for the results header.
+func (t ThinkTime) String() string {
+	switch t.Distribution {
+	case DistributionFixed:
+		return t.Mean.String()
+	case DistributionUniform:
+		return fmt.Sprintf("uniform %v-%v", t.Min, t.Max)
+	case DistributionNormal:
+		return fmt.Sprintf("normal mean %v, stddev %v", t.Mean, t.StdDev)
+	case DistributionExponential:
+		return fmt.Sprintf("exponential mean %v", t.Mean)
+	}
+	return "none"
+}
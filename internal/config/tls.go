@@ -0,0 +1,122 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TLSOptions holds the raw client-side TLS settings.
+type TLSOptions struct {
+	CertFile     string
+	KeyFile      string
+	CAFile       string
+	Insecure     bool
+	ServerName   string
+	MinVersion   string
+	MaxVersion   string
+	CipherSuites string
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ParseTLSVersion converts a version such as "1.2" into its tls constant.
+func ParseTLSVersion(s string) (uint16, error) {
+	v, ok := tlsVersions[strings.TrimPrefix(strings.ToLower(s), "tls")]
+	if !ok {
+		return 0, fmt.Errorf("invalid TLS version %q: must be 1.0, 1.1, 1.2 or 1.3", s)
+	}
+	return v, nil
+}
+
+// ParseCipherSuites converts a comma separated list of cipher suite names
+// (as printed by crypto/tls) into their IDs.
+func ParseCipherSuites(s string) ([]uint16, error) {
+	known := make(map[string]uint16)
+	for _, cs := range tls.CipherSuites() {
+		known[cs.Name] = cs.ID
+	}
+	for _, cs := range tls.InsecureCipherSuites() {
+		known[cs.Name] = cs.ID
+	}
+
+	var ids []uint16
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// NewTLSConfig builds a client tls.Config from the given options.
+// It returns nil when no option is set, so the transport defaults apply.
+func NewTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	if opts == (TLSOptions{}) {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{
+		InsecureSkipVerify: opts.Insecure,
+		ServerName:         opts.ServerName,
+	}
+
+	if (opts.CertFile == "") != (opts.KeyFile == "") {
+		return nil, fmt.Errorf("client certificate and key must be provided together")
+	}
+	if opts.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %q", opts.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	var err error
+	if opts.MinVersion != "" {
+		if cfg.MinVersion, err = ParseTLSVersion(opts.MinVersion); err != nil {
+			return nil, err
+		}
+	}
+	if opts.MaxVersion != "" {
+		if cfg.MaxVersion, err = ParseTLSVersion(opts.MaxVersion); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.MinVersion != 0 && cfg.MaxVersion != 0 && cfg.MinVersion > cfg.MaxVersion {
+		return nil, fmt.Errorf("minimum TLS version %s is above maximum %s", opts.MinVersion, opts.MaxVersion)
+	}
+
+	if opts.CipherSuites != "" {
+		if cfg.CipherSuites, err = ParseCipherSuites(opts.CipherSuites); err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg, nil
+}
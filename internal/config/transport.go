@@ -0,0 +1,50 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Transport holds connection-level settings for the HTTP client.
+// Zero values mean "use the default".
+type Transport struct {
+	Timeout               time.Duration
+	ConnectTimeout        time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	MaxIdleConns          int
+	MaxConnsPerHost       int
+	DisableKeepAlives     bool
+	// DNSCacheTTL caches host name lookups per HTTP client; zero resolves
+	// on every new connection.
+	DNSCacheTTL time.Duration
+}
+
+// Validate checks that no timeout or pool size is negative.
+func (t Transport) Validate() error {
+	timeouts := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"timeout", t.Timeout},
+		{"connect timeout", t.ConnectTimeout},
+		{"TLS handshake timeout", t.TLSHandshakeTimeout},
+		{"response header timeout", t.ResponseHeaderTimeout},
+		{"DNS cache TTL", t.DNSCacheTTL},
+	}
+	for _, to := range timeouts {
+		if to.value < 0 {
+			return fmt.Errorf("%s cannot be negative, got %v", to.name, to.value)
+		}
+	}
+
+	if t.MaxIdleConns < 0 {
+		return fmt.Errorf("max idle connections cannot be negative, got %d", t.MaxIdleConns)
+	}
+
+	if t.MaxConnsPerHost < 0 {
+		return fmt.Errorf("max connections per host cannot be negative, got %d", t.MaxConnsPerHost)
+	}
+
+	return nil
+}
@@ -3,31 +3,58 @@ package config
 import (
 	"fmt"
 	"net/url"
+	"strings"
 )
 
 type URI struct {
 	raw    string
 	parsed *url.URL
+	socket string
 }
 
-// NewURI validates and constructs a URI.
+// NewURI validates and constructs a URI. Besides http and https URLs it
+// accepts unix:///path/to.sock targets, optionally followed by the request
+// path after a colon (unix:///path/to.sock:/health).
 func NewURI(s string) (URI, error) {
 	if s == "" {
 		return URI{}, fmt.Errorf("URI cannot be empty")
 	}
 
+	if strings.HasPrefix(s, "unix://") {
+		return newUnixURI(s)
+	}
+
 	parsed, err := url.ParseRequestURI(s)
 	if err != nil {
 		return URI{}, fmt.Errorf("invalid URI %q: %w", s, err)
 	}
 
 	if parsed.Scheme != "http" && parsed.Scheme != "https" {
-		return URI{}, fmt.Errorf("URI must use http or https scheme: %q", s)
+		return URI{}, fmt.Errorf("URI must use http, https or unix scheme: %q", s)
 	}
 
 	return URI{raw: s, parsed: parsed}, nil
 }
 
+// newUnixURI parses a unix:// target into the socket path and an http URL
+// for the request itself.
+func newUnixURI(s string) (URI, error) {
+	socket, path, _ := strings.Cut(strings.TrimPrefix(s, "unix://"), ":")
+	if !strings.HasPrefix(socket, "/") {
+		return URI{}, fmt.Errorf("unix socket path must be absolute: %q", s)
+	}
+	if path == "" {
+		path = "/"
+	}
+
+	parsed, err := url.ParseRequestURI("http://localhost" + path)
+	if err != nil {
+		return URI{}, fmt.Errorf("invalid request path in %q: %w", s, err)
+	}
+
+	return URI{raw: s, parsed: parsed, socket: socket}, nil
+}
+
 // String returns the raw URI string.
 func (u URI) String() string {
 	return u.raw
@@ -37,3 +64,29 @@ func (u URI) String() string {
 func (u URI) URL() *url.URL {
 	return u.parsed
 }
+
+// RequestURL returns the URL requests are sent to. For unix socket targets
+// this is an http://localhost URL carrying the request path.
+func (u URI) RequestURL() string {
+	if u.socket != "" {
+		return u.parsed.String()
+	}
+	return u.raw
+}
+
+// SocketPath returns the unix socket path, or "" for TCP targets.
+func (u URI) SocketPath() string {
+	return u.socket
+}
+
+// WithPath returns the URI with its path and query replaced by p, which
+// must start with a slash.
+func (u URI) WithPath(p string) (URI, error) {
+	if !strings.HasPrefix(p, "/") {
+		return URI{}, fmt.Errorf("invalid request path %q: must start with /", p)
+	}
+	if u.socket != "" {
+		return NewURI("unix://" + u.socket + ":" + p)
+	}
+	return NewURI(u.parsed.Scheme + "://" + u.parsed.Host + p)
+}
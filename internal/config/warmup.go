@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Warmup is traffic sent before the measured run to fill connection pools
+// and caches and let the target's JIT settle. It is left out of the
+// results, and the clock starts when it ends.
+type Warmup struct {
+	// Duration warms up for this long.
+	Duration time.Duration
+	// Requests warms up with this many requests across all virtual users.
+	Requests int
+}
+
+// ParseWarmup parses a warm-up given as a duration ("30s") or a number of
+// requests ("500"). An empty string means no warm-up.
+func ParseWarmup(s string) (Warmup, error) {
+	if s == "" {
+		return Warmup{}, nil
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 0 {
+			return Warmup{}, fmt.Errorf("invalid warm-up %q: cannot be negative", s)
+		}
+		return Warmup{Requests: n}, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return Warmup{}, fmt.Errorf("invalid warm-up %q: must be a duration such as 30s or a number of requests", s)
+	}
+	return Warmup{Duration: d}, nil
+}
+
+// IsZero reports whether no warm-up is configured.
+func (w Warmup) IsZero() bool {
+	return w.Duration == 0 && w.Requests == 0
+}
+
+// String describes the warm-up for the results header.
+func (w Warmup) String() string {
+	if w.Requests > 0 {
+		return fmt.Sprintf("%d requests", w.Requests)
+	}
+	return w.Duration.String()
+}
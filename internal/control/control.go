@@ -0,0 +1,142 @@
+// Package control changes the load of a running test from outside it:
+// commands typed in the terminal and signals.
+package control
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Target is a running test whose load can change.
+type Target interface {
+	Users() int
+	SetUsers(n int, source string) error
+	MaxRPS() float64
+	SetMaxRPS(rps float64, source string) error
+	Paused() bool
+	Pause(source string) error
+	Resume(source string) error
+}
+
+// Help lists the commands understood by Exec.
+const Help = `Commands:
+  +[N]         add N virtual users (default the adjust step)
+  -[N]         remove N virtual users (default the adjust step)
+  users N      run N virtual users
+  rate N       cap the requests per second at N (0 or off removes the cap)
+  pause        stop sending new requests, keeping connections open for
+               up to 90s
+  resume       carry on after a pause
+  p            pause or resume
+  status       show the current load
+  help         show this help`
+
+// Exec runs a single command against t. step is the number of users added
+// or removed by a bare + or -, and source names where the command came
+// from for the results timeline. It returns a line describing the load
+// after the command.
+func Exec(t Target, command string, step int, source string) (string, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty command")
+	}
+
+	verb, arg := fields[0], ""
+	if len(fields) > 2 {
+		return "", fmt.Errorf("unknown command %q, type help for the list", command)
+	}
+	if len(fields) == 2 {
+		arg = fields[1]
+	}
+	// "+5" and "-5" carry their argument in the verb.
+	if sign := verb[0]; (sign == '+' || sign == '-') && arg == "" {
+		verb, arg = verb[:1], verb[1:]
+	}
+
+	var err error
+	switch verb {
+	case "+", "-":
+		n := step
+		if arg != "" {
+			if n, err = strconv.Atoi(arg); err != nil || n < 0 {
+				return "", fmt.Errorf("invalid number of users %q", arg)
+			}
+		}
+		if verb == "-" {
+			n = -n
+		}
+		err = t.SetUsers(max(t.Users()+n, 0), source)
+	case "users":
+		n, convErr := strconv.Atoi(arg)
+		if convErr != nil {
+			return "", fmt.Errorf("invalid number of users %q", arg)
+		}
+		err = t.SetUsers(n, source)
+	case "rate":
+		rps := 0.0
+		if arg != "off" {
+			if rps, err = strconv.ParseFloat(arg, 64); err != nil {
+				return "", fmt.Errorf("invalid rate %q", arg)
+			}
+		}
+		err = t.SetMaxRPS(rps, source)
+	case "pause":
+		err = t.Pause(source)
+	case "resume":
+		err = t.Resume(source)
+	case "p":
+		err = Toggle(t, source)
+	case "status":
+	case "help", "?":
+		return Help, nil
+	default:
+		return "", fmt.Errorf("unknown command %q, type help for the list", command)
+	}
+	if err != nil {
+		return "", err
+	}
+	return Status(t), nil
+}
+
+// Toggle pauses t when it is running and resumes it when it is paused.
+func Toggle(t Target, source string) error {
+	if t.Paused() {
+		return t.Resume(source)
+	}
+	return t.Pause(source)
+}
+
+// Status describes the current load of t.
+func Status(t Target) string {
+	rate := "unlimited"
+	if rps := t.MaxRPS(); rps > 0 {
+		rate = strconv.FormatFloat(rps, 'g', -1, 64)
+	}
+	status := fmt.Sprintf("users: %d, max rps: %s", t.Users(), rate)
+	if t.Paused() {
+		status += " (paused)"
+	}
+	return status
+}
+
+// ReadCommands runs every line read from r as a command against t until r
+// is exhausted, writing the replies and errors to w.
+func ReadCommands(r io.Reader, w io.Writer, t Target, step int) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		reply, err := Exec(t, line, step, "terminal")
+		if err != nil {
+			fmt.Fprintf(w, "error: %v\n", err)
+			continue
+		}
+		fmt.Fprintln(w, reply)
+	}
+	return scanner.Err()
+}
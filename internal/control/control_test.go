@@ -0,0 +1,123 @@
+package control
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type fakeTarget struct {
+	users   int
+	rps     float64
+	paused  bool
+	sources []string
+}
+
+func (f *fakeTarget) Users() int      { return f.users }
+func (f *fakeTarget) MaxRPS() float64 { return f.rps }
+func (f *fakeTarget) Paused() bool    { return f.paused }
+
+func (f *fakeTarget) Pause(source string) error {
+	if f.paused {
+		return errors.New("already paused")
+	}
+	f.paused = true
+	return nil
+}
+
+func (f *fakeTarget) Resume(source string) error {
+	if !f.paused {
+		return errors.New("not paused")
+	}
+	f.paused = false
+	return nil
+}
+
+func (f *fakeTarget) SetUsers(n int, source string) error {
+	f.users = n
+	f.sources = append(f.sources, source)
+	return nil
+}
+
+func (f *fakeTarget) SetMaxRPS(rps float64, source string) error {
+	f.rps = rps
+	f.sources = append(f.sources, source)
+	return nil
+}
+
+func TestExec(t *testing.T) {
+	target := &fakeTarget{users: 10}
+
+	tests := []struct {
+		command string
+		users   int
+		rps     float64
+	}{
+		{"+", 15, 0},
+		{"+20", 35, 0},
+		{"- 5", 30, 0},
+		{"-100", 0, 0},
+		{"users 12", 12, 0},
+		{"rate 50", 12, 50},
+		{"rate 2.5", 12, 2.5},
+		{"rate off", 12, 0},
+		{"status", 12, 0},
+	}
+	for _, tt := range tests {
+		if _, err := Exec(target, tt.command, 5, "test"); err != nil {
+			t.Fatalf("Exec(%q) unexpected error: %v", tt.command, err)
+		}
+		if target.users != tt.users || target.rps != tt.rps {
+			t.Errorf("after %q: users = %d, rps = %g; want %d and %g", tt.command, target.users, target.rps, tt.users, tt.rps)
+		}
+	}
+
+	for _, command := range []string{"", "faster", "users", "users many", "+x", "rate fast", "users 1 2"} {
+		if _, err := Exec(target, command, 5, "test"); err == nil {
+			t.Errorf("Exec(%q) expected error", command)
+		}
+	}
+}
+
+func TestExec_Pause(t *testing.T) {
+	target := &fakeTarget{users: 3}
+
+	reply, err := Exec(target, "pause", 1, "test")
+	if err != nil || !target.paused || reply != "users: 3, max rps: unlimited (paused)" {
+		t.Fatalf("pause: reply = %q, err = %v, paused = %v", reply, err, target.paused)
+	}
+	if _, err := Exec(target, "pause", 1, "test"); err == nil {
+		t.Error("pausing twice should fail")
+	}
+	if _, err := Exec(target, "p", 1, "test"); err != nil || target.paused {
+		t.Errorf("p should resume a paused test: err = %v, paused = %v", err, target.paused)
+	}
+	if _, err := Exec(target, "p", 1, "test"); err != nil || !target.paused {
+		t.Errorf("p should pause a running test: err = %v, paused = %v", err, target.paused)
+	}
+	if _, err := Exec(target, "resume", 1, "test"); err != nil || target.paused {
+		t.Errorf("resume: err = %v, paused = %v", err, target.paused)
+	}
+}
+
+func TestReadCommands(t *testing.T) {
+	target := &fakeTarget{users: 4}
+	var out bytes.Buffer
+
+	input := "+\n\nbogus\nrate 10\n"
+	if err := ReadCommands(strings.NewReader(input), &out, target, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if target.users != 6 || target.rps != 10 {
+		t.Errorf("users = %d, rps = %g; want 6 and 10", target.users, target.rps)
+	}
+	if len(target.sources) != 2 || target.sources[0] != "terminal" {
+		t.Errorf("sources = %v, want two changes from the terminal", target.sources)
+	}
+	want := "users: 6, max rps: unlimited\nerror: unknown command \"bogus\", type help for the list\nusers: 6, max rps: 10\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
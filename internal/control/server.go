@@ -0,0 +1,180 @@
+package control
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/EsteveSegura/BrickHauler/internal/metrics"
+	"github.com/EsteveSegura/BrickHauler/internal/runner"
+)
+
+// Runner is a running test that the HTTP API can watch and stop as well as
+// adjust.
+type Runner interface {
+	Target
+	// Progress returns the metrics so far and the time the test has been
+	// running.
+	Progress() (metrics.Snapshot, time.Duration)
+	// Adjust changes the users, the rate limit or both, leaving nil values
+	// as they are and applying neither if either is invalid.
+	Adjust(users *int, maxRPS *float64, source string) error
+}
+
+// apiSource names the HTTP API in the results timeline.
+const apiSource = "control API"
+
+// Snapshot is the JSON view of a running test returned by the HTTP API.
+type Snapshot struct {
+	ElapsedSeconds    float64          `json:"elapsed_seconds"`
+	Users             int              `json:"users"`
+	MaxRPS            float64          `json:"max_rps"`
+	Paused            bool             `json:"paused"`
+	Stopping          bool             `json:"stopping"`
+	Successful        int64            `json:"successful"`
+	Failed            int64            `json:"failed"`
+	RequestsPerSecond float64          `json:"requests_per_second"`
+	Latency           Latency          `json:"latency_ms"`
+	Protocols         map[string]int64 `json:"protocols,omitempty"`
+}
+
+// Latency summarizes the response times of successful requests, in
+// milliseconds.
+type Latency struct {
+	Avg float64 `json:"avg"`
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+// Adjustment is the body of POST /adjust. Fields left out keep their value.
+type Adjustment struct {
+	Users  *int     `json:"users"`
+	MaxRPS *float64 `json:"max_rps"`
+}
+
+// NewHandler returns the HTTP control API for run:
+//
+//	GET  /snapshot  current metrics and load as JSON
+//	POST /adjust    change the users or rate, from an Adjustment
+//	POST /pause     hold the load
+//	POST /resume    carry on after a pause
+//	POST /stop      stop gracefully, like Ctrl+C
+//
+// Adjusting, pausing and resuming answer 409 Conflict with
+// runner.ErrNotAdjustable unless the run is adjustable. stop is called at most once, by POST /stop, with the source to log in the
+// results timeline.
+func NewHandler(run Runner, stop func(source string)) http.Handler {
+	h := &handler{run: run, stop: stop}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /snapshot", h.snapshot)
+	mux.HandleFunc("POST /adjust", h.adjust)
+	mux.HandleFunc("POST /pause", h.pause)
+	mux.HandleFunc("POST /resume", h.resume)
+	mux.HandleFunc("POST /stop", h.stopRun)
+	return mux
+}
+
+type handler struct {
+	run  Runner
+	stop func(source string)
+	// stopping is set once POST /stop has been called.
+	stopping atomic.Bool
+}
+
+func (h *handler) snapshot(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.current())
+}
+
+func (h *handler) adjust(w http.ResponseWriter, r *http.Request) {
+	var adj Adjustment
+	if err := json.NewDecoder(r.Body).Decode(&adj); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid adjustment: %w", err))
+		return
+	}
+	if adj.Users == nil && adj.MaxRPS == nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("set users, max_rps or both"))
+		return
+	}
+
+	if err := h.run.Adjust(adj.Users, adj.MaxRPS, apiSource); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, runner.ErrNotAdjustable) {
+			status = http.StatusConflict
+		}
+		writeError(w, status, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.current())
+}
+
+func (h *handler) pause(w http.ResponseWriter, r *http.Request) {
+	h.apply(w, h.run.Pause)
+}
+
+func (h *handler) resume(w http.ResponseWriter, r *http.Request) {
+	h.apply(w, h.run.Resume)
+}
+
+func (h *handler) apply(w http.ResponseWriter, change func(source string) error) {
+	if err := change(apiSource); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.current())
+}
+
+func (h *handler) stopRun(w http.ResponseWriter, r *http.Request) {
+	if !h.stopping.Swap(true) {
+		h.stop(apiSource)
+	}
+	writeJSON(w, http.StatusAccepted, h.current())
+}
+
+// current builds the snapshot of the run right now.
+func (h *handler) current() Snapshot {
+	snap, elapsed := h.run.Progress()
+
+	s := Snapshot{
+		ElapsedSeconds: elapsed.Seconds(),
+		Users:          h.run.Users(),
+		MaxRPS:         h.run.MaxRPS(),
+		Paused:         h.run.Paused(),
+		Stopping:       h.stopping.Load(),
+		Successful:     snap.SuccessCount,
+		Failed:         snap.FailureCount,
+		Latency: Latency{
+			Avg: milliseconds(snap.AverageTime()),
+			P50: milliseconds(snap.Percentile(50)),
+			P90: milliseconds(snap.Percentile(90)),
+			P95: milliseconds(snap.Percentile(95)),
+			P99: milliseconds(snap.Percentile(99)),
+			Max: milliseconds(snap.Percentile(100)),
+		},
+		Protocols: snap.Protocols,
+	}
+	if elapsed > 0 {
+		s.RequestsPerSecond = float64(snap.TotalRequests()) / elapsed.Seconds()
+	}
+	return s
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,181 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EsteveSegura/BrickHauler/internal/config"
+	"github.com/EsteveSegura/BrickHauler/internal/metrics"
+	"github.com/EsteveSegura/BrickHauler/internal/runner"
+)
+
+type fakeRunner struct {
+	fakeTarget
+	// fixed makes the load of the run not adjustable.
+	fixed bool
+}
+
+func (f *fakeRunner) Adjust(users *int, maxRPS *float64, source string) error {
+	switch {
+	case f.fixed:
+		return runner.ErrNotAdjustable
+	case users != nil && *users < 0, maxRPS != nil && *maxRPS < 0:
+		return errors.New("cannot be negative")
+	}
+	if users != nil {
+		f.SetUsers(*users, source)
+	}
+	if maxRPS != nil {
+		f.SetMaxRPS(*maxRPS, source)
+	}
+	return nil
+}
+
+func (f *fakeRunner) Progress() (metrics.Snapshot, time.Duration) {
+	m := metrics.New(0)
+	for i := 1; i <= 10; i++ {
+		m.RecordSuccess(time.Duration(i) * time.Millisecond)
+	}
+	m.RecordFailure()
+	m.RecordProtocol("HTTP/1.1")
+	return m.Snapshot(), 2 * time.Second
+}
+
+func do(t *testing.T, h http.Handler, method, path, body string) (*httptest.ResponseRecorder, Snapshot) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+
+	var snap Snapshot
+	if rec.Code < 300 {
+		if err := json.Unmarshal(rec.Body.Bytes(), &snap); err != nil {
+			t.Fatalf("%s %s: invalid JSON %q: %v", method, path, rec.Body.String(), err)
+		}
+	}
+	return rec, snap
+}
+
+func TestHandler_Snapshot(t *testing.T) {
+	h := NewHandler(&fakeRunner{fakeTarget: fakeTarget{users: 5, rps: 20}}, func(string) {})
+
+	rec, snap := do(t, h, "GET", "/snapshot", "")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("status = %d, content type = %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if snap.Successful != 10 || snap.Failed != 1 || snap.RequestsPerSecond != 5.5 {
+		t.Errorf("snapshot = %+v, want 10 successful, 1 failed at 5.5 rps", snap)
+	}
+	if snap.Users != 5 || snap.MaxRPS != 20 || snap.ElapsedSeconds != 2 {
+		t.Errorf("snapshot = %+v, want 5 users at 20 max rps after 2s", snap)
+	}
+	if snap.Latency.P50 != 6 || snap.Latency.Max != 10 || snap.Protocols["HTTP/1.1"] != 1 {
+		t.Errorf("latency = %+v, protocols = %v", snap.Latency, snap.Protocols)
+	}
+
+	if rec, _ := do(t, h, "POST", "/snapshot", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /snapshot status = %d, want 405", rec.Code)
+	}
+}
+
+func TestHandler_Adjust(t *testing.T) {
+	run := &fakeRunner{fakeTarget: fakeTarget{users: 5}}
+	h := NewHandler(run, func(string) {})
+
+	rec, snap := do(t, h, "POST", "/adjust", `{"users": 12, "max_rps": 40}`)
+	if rec.Code != http.StatusOK || snap.Users != 12 || snap.MaxRPS != 40 {
+		t.Fatalf("status = %d, snapshot = %+v; want 12 users at 40 rps", rec.Code, snap)
+	}
+	if run.sources[0] != "control API" {
+		t.Errorf("source = %q, want control API", run.sources[0])
+	}
+
+	if _, snap := do(t, h, "POST", "/adjust", `{"max_rps": 0}`); snap.Users != 12 || snap.MaxRPS != 0 {
+		t.Errorf("snapshot = %+v, want users kept and the rate limit removed", snap)
+	}
+
+	for _, body := range []string{"", "{}", `{"users": "many"}`, `{"users": 3, "max_rps": -1}`} {
+		if rec, _ := do(t, h, "POST", "/adjust", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("POST /adjust %q status = %d, want 400", body, rec.Code)
+		}
+	}
+	if run.users != 12 {
+		t.Errorf("users = %d after rejected adjustments, want 12", run.users)
+	}
+
+	run.fixed = true
+	if rec, _ := do(t, h, "POST", "/adjust", `{"users": 3}`); rec.Code != http.StatusConflict {
+		t.Errorf("POST /adjust on a fixed run status = %d, want 409", rec.Code)
+	}
+}
+
+func TestHandler_PauseResumeStop(t *testing.T) {
+	stops := 0
+	h := NewHandler(&fakeRunner{}, func(string) { stops++ })
+
+	if _, snap := do(t, h, "POST", "/pause", ""); !snap.Paused {
+		t.Error("POST /pause did not pause")
+	}
+	if rec, _ := do(t, h, "POST", "/pause", ""); rec.Code != http.StatusConflict {
+		t.Errorf("pausing twice status = %d, want 409", rec.Code)
+	}
+	if _, snap := do(t, h, "POST", "/resume", ""); snap.Paused {
+		t.Error("POST /resume did not resume")
+	}
+
+	rec, snap := do(t, h, "POST", "/stop", "")
+	if rec.Code != http.StatusAccepted || !snap.Stopping {
+		t.Errorf("POST /stop status = %d, snapshot = %+v", rec.Code, snap)
+	}
+	do(t, h, "POST", "/stop", "")
+	if stops != 1 {
+		t.Errorf("stop called %d times, want once", stops)
+	}
+}
+
+func TestHandler_FixedRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	// A profile that drops to no users ends the run long before the budget
+	// is spent, with the API watching but unable to change the load.
+	uri, _ := config.NewURI(server.URL)
+	profile, err := config.ParseProfile("spike:0,2,0s,200ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{URI: uri, Method: config.MethodGET, Concurrency: 2, Requests: 100000, Profile: profile}
+	run := runner.New(cfg, io.Discard)
+	h := NewHandler(run, run.Stop)
+
+	done := make(chan error, 1)
+	go func() { done <- run.Run(context.Background()) }()
+
+	time.Sleep(50 * time.Millisecond)
+	if rec, _ := do(t, h, "GET", "/snapshot", ""); rec.Code != http.StatusOK {
+		t.Errorf("GET /snapshot status = %d, want 200", rec.Code)
+	}
+	for _, path := range []string{"/adjust", "/pause", "/resume"} {
+		rec, _ := do(t, h, "POST", path, `{"users": 5}`)
+		if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), runner.ErrNotAdjustable.Error()) {
+			t.Errorf("POST %s = %d %s, want 409 not adjustable", path, rec.Code, rec.Body)
+		}
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the run did not end after the profile dropped to zero users")
+	}
+}
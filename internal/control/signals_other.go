@@ -0,0 +1,12 @@
+//go:build !unix
+
+package control
+
+import (
+	"context"
+	"io"
+)
+
+// NotifySignals does nothing: SIGUSR1, SIGUSR2 and SIGTSTP only exist on
+// Unix.
+func NotifySignals(ctx context.Context, w io.Writer, t Target, step int, terminal bool) {}
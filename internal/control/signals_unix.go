@@ -0,0 +1,51 @@
+//go:build unix
+
+package control
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// NotifySignals adds step users to t on SIGUSR1 and removes step users on
+// SIGUSR2 until ctx is done, writing the new load to w. With terminal set,
+// because the commands are typed in one, SIGTSTP (Ctrl+Z) pauses or resumes
+// t instead of suspending the process; otherwise it is left to job control.
+func NotifySignals(ctx context.Context, w io.Writer, t Target, step int, terminal bool) {
+	signals := []os.Signal{syscall.SIGUSR1, syscall.SIGUSR2}
+	if terminal {
+		signals = append(signals, syscall.SIGTSTP)
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-ch:
+				var command, source string
+				switch sig {
+				case syscall.SIGUSR1:
+					command, source = "+", "SIGUSR1"
+				case syscall.SIGUSR2:
+					command, source = "-", "SIGUSR2"
+				default:
+					command, source = "p", "SIGTSTP"
+				}
+				reply, err := Exec(t, command, step, source)
+				if err != nil {
+					fmt.Fprintf(w, "%s: %v\n", source, err)
+					continue
+				}
+				fmt.Fprintf(w, "%s: %s\n", source, reply)
+			}
+		}
+	}()
+}
@@ -0,0 +1,80 @@
+//go:build unix
+
+package control
+
+import (
+	"context"
+	"io"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// lockedTarget guards fakeTarget, which the signal goroutine updates.
+type lockedTarget struct {
+	mu sync.Mutex
+	fakeTarget
+}
+
+func (l *lockedTarget) Users() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.fakeTarget.Users()
+}
+
+func (l *lockedTarget) SetUsers(n int, source string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.fakeTarget.SetUsers(n, source)
+}
+
+func (l *lockedTarget) Paused() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.fakeTarget.Paused()
+}
+
+func (l *lockedTarget) Pause(source string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.fakeTarget.Pause(source)
+}
+
+func (l *lockedTarget) Resume(source string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.fakeTarget.Resume(source)
+}
+
+func TestNotifySignals(t *testing.T) {
+	target := &lockedTarget{fakeTarget: fakeTarget{users: 10}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	NotifySignals(ctx, io.Discard, target, 3, true)
+
+	waitFor := func(users int) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for target.Users() != users {
+			if time.Now().After(deadline) {
+				t.Fatalf("users = %d, want %d", target.Users(), users)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	waitFor(13)
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR2)
+	waitFor(10)
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGTSTP)
+	deadline := time.Now().Add(2 * time.Second)
+	for !target.Paused() {
+		if time.Now().After(deadline) {
+			t.Fatal("SIGTSTP did not pause the test")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,434 @@
+// Package curl parses curl command lines, such as those copied from a
+// browser's developer tools, into the pieces BrickHauler understands.
+package curl
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/EsteveSegura/BrickHauler/internal/scenario"
+)
+
+// Command is a parsed curl invocation.
+type Command struct {
+	Method  string
+	URL     string
+	Headers []string // "Name: value"
+	Cookies []string // "name=value"
+	Body    string
+
+	Insecure    bool
+	Resolve     []string
+	Proxy       string
+	ProxyAuth   string
+	CACert      string
+	Cert        string
+	Key         string
+	HTTPVersion string
+	UnixSocket  string
+	Timeout     time.Duration
+	ConnTimeout time.Duration
+}
+
+// Parse parses a curl command line. The leading "curl" is optional. Bodies
+// given as @file are read from disk, as curl would.
+func Parse(cmdline string) (*Command, error) {
+	args, err := split(cmdline)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) > 0 && args[0] == "curl" {
+		args = args[1:]
+	}
+
+	p := &parser{cmd: &Command{}}
+	if err := p.parse(args); err != nil {
+		return nil, err
+	}
+	return p.finish()
+}
+
+type parser struct {
+	cmd      *Command
+	data     []string
+	getData  bool
+	jsonBody bool
+}
+
+// valueOptions are the options that take an argument. Most of them only
+// affect how curl itself behaves and are ignored, but they must be known so
+// that their argument is not taken for the URL.
+var valueOptions = map[string]bool{
+	"-X": true, "--request": true,
+	"-H": true, "--header": true,
+	"-d": true, "--data": true, "--data-ascii": true, "--data-raw": true,
+	"--data-binary": true, "--data-urlencode": true, "--json": true,
+	"-b": true, "--cookie": true,
+	"-u": true, "--user": true,
+	"-A": true, "--user-agent": true,
+	"-e": true, "--referer": true,
+	"-x": true, "--proxy": true,
+	"-U": true, "--proxy-user": true,
+	"--resolve": true, "--url": true, "--unix-socket": true,
+	"--cacert": true, "-E": true, "--cert": true, "--key": true,
+	"-m": true, "--max-time": true, "--connect-timeout": true,
+	"-o": true, "--output": true, "-w": true, "--write-out": true,
+	"-c": true, "--cookie-jar": true,
+	"-F": true, "--form": true, "--form-string": true,
+
+	// Ignored.
+	"-C": true, "--continue-at": true,
+	"-D": true, "--dump-header": true,
+	"-K": true, "--config": true,
+	"-P": true, "--ftp-port": true,
+	"-Q": true, "--quote": true,
+	"-r": true, "--range": true,
+	"-t": true, "--telnet-option": true,
+	"-T": true, "--upload-file": true,
+	"-y": true, "--speed-time": true,
+	"-Y": true, "--speed-limit": true,
+	"-z": true, "--time-cond": true,
+	"--abstract-unix-socket": true, "--alt-svc": true, "--aws-sigv4": true,
+	"--capath": true, "--cert-type": true, "--ciphers": true,
+	"--connect-to": true, "--create-file-mode": true, "--crlfile": true,
+	"--curves": true, "--delegation": true, "--dns-interface": true,
+	"--dns-ipv4-addr": true, "--dns-ipv6-addr": true, "--dns-servers": true,
+	"--doh-url": true, "--ech": true, "--egd-file": true, "--engine": true,
+	"--etag-compare": true, "--etag-save": true, "--expect100-timeout": true,
+	"--happy-eyeballs-timeout-ms": true, "--haproxy-clientip": true,
+	"--hostpubmd5": true, "--hostpubsha256": true, "--hsts": true,
+	"--interface": true, "--ip-tos": true, "--ipfs-gateway": true,
+	"--keepalive-time": true, "--key-type": true, "--krb": true,
+	"--libcurl": true, "--limit-rate": true, "--local-port": true,
+	"--login-options": true, "--mail-auth": true, "--mail-from": true,
+	"--mail-rcpt": true, "--max-filesize": true, "--max-redirs": true,
+	"--netrc-file": true, "--noproxy": true, "--oauth2-bearer": true,
+	"--output-dir": true, "--parallel-max": true, "--pass": true,
+	"--pinnedpubkey": true, "--preproxy": true, "--proto": true,
+	"--proto-default": true, "--proto-redir": true, "--proxy-cacert": true,
+	"--proxy-capath": true, "--proxy-cert": true, "--proxy-cert-type": true,
+	"--proxy-ciphers": true, "--proxy-crlfile": true, "--proxy-header": true,
+	"--proxy-key": true, "--proxy-key-type": true, "--proxy-pass": true,
+	"--proxy-pinnedpubkey": true, "--proxy-service-name": true,
+	"--proxy-tls13-ciphers": true, "--proxy-tlsauthtype": true,
+	"--proxy-tlspassword": true, "--proxy-tlsuser": true, "--proxy1.0": true,
+	"--pubkey": true, "--random-file": true, "--rate": true,
+	"--request-target": true, "--retry": true, "--retry-delay": true,
+	"--retry-max-time": true, "--sasl-authzid": true, "--service-name": true,
+	"--socks4": true, "--socks4a": true, "--socks5": true,
+	"--socks5-gssapi-service": true, "--socks5-hostname": true,
+	"--stderr": true, "--tftp-blksize": true, "--tls-max": true,
+	"--tls13-ciphers": true, "--tlsauthtype": true, "--tlspassword": true,
+	"--tlsuser": true, "--trace": true, "--trace-ascii": true,
+	"--trace-config": true, "--url-query": true, "--variable": true,
+}
+
+func (p *parser) parse(args []string) error {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			if p.cmd.URL != "" {
+				return fmt.Errorf("more than one URL given: %q and %q", p.cmd.URL, arg)
+			}
+			p.cmd.URL = arg
+			continue
+		}
+
+		name, value, hasValue := arg, "", false
+		if strings.HasPrefix(arg, "--") {
+			if n, v, ok := strings.Cut(arg, "="); ok && valueOptions[n] {
+				name, value, hasValue = n, v, true
+			}
+		} else if len(arg) > 2 {
+			// Short options may be bundled (-sSL) and carry their value
+			// (-XPOST). The first one that takes a value ends the bundle,
+			// with the rest of it or the next argument as its value (-sXPUT).
+			name = ""
+			for j := 1; j < len(arg); j++ {
+				opt := "-" + arg[j:j+1]
+				if !valueOptions[opt] {
+					if err := p.flag(opt); err != nil {
+						return err
+					}
+					continue
+				}
+				name = opt
+				if j+1 < len(arg) {
+					value, hasValue = arg[j+1:], true
+				}
+				break
+			}
+			if name == "" {
+				continue
+			}
+		}
+
+		if !valueOptions[name] {
+			if err := p.flag(name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !hasValue {
+			i++
+			if i >= len(args) {
+				return fmt.Errorf("option %s requires a value", name)
+			}
+			value = args[i]
+		}
+		if err := p.option(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flag handles options without a value.
+func (p *parser) flag(name string) error {
+	switch name {
+	case "-k", "--insecure":
+		p.cmd.Insecure = true
+	case "-G", "--get":
+		p.getData = true
+	case "-I", "--head":
+		p.cmd.Method = "HEAD"
+	case "--http1.1", "--http1.0":
+		p.cmd.HTTPVersion = "1.1"
+	case "--http2":
+		p.cmd.HTTPVersion = "2"
+	case "--http2-prior-knowledge":
+		p.cmd.HTTPVersion = "h2c"
+	}
+	// Everything else (-s, -L, -v, --compressed, ...) only affects how
+	// curl itself behaves and is ignored.
+	return nil
+}
+
+// option handles options with a value.
+func (p *parser) option(name, value string) error {
+	switch name {
+	case "-X", "--request":
+		p.cmd.Method = strings.ToUpper(value)
+	case "-H", "--header":
+		p.cmd.Headers = append(p.cmd.Headers, value)
+	case "-d", "--data", "--data-ascii":
+		data, err := readData(value, true)
+		if err != nil {
+			return err
+		}
+		p.data = append(p.data, data)
+	case "--data-binary":
+		data, err := readData(value, false)
+		if err != nil {
+			return err
+		}
+		p.data = append(p.data, data)
+	case "--data-raw":
+		p.data = append(p.data, value)
+	case "--data-urlencode":
+		p.data = append(p.data, urlencode(value))
+	case "--json":
+		data, err := readData(value, false)
+		if err != nil {
+			return err
+		}
+		p.data = append(p.data, data)
+		p.jsonBody = true
+	case "-b", "--cookie":
+		if !strings.Contains(value, "=") {
+			return fmt.Errorf("cookie files are not supported: %q", value)
+		}
+		for _, c := range strings.Split(value, ";") {
+			if c = strings.TrimSpace(c); c != "" {
+				p.cmd.Cookies = append(p.cmd.Cookies, c)
+			}
+		}
+	case "-F", "--form", "--form-string":
+		return fmt.Errorf("multipart forms are not supported: %s %q", name, value)
+	case "-u", "--user":
+		auth := base64.StdEncoding.EncodeToString([]byte(value))
+		p.cmd.Headers = append(p.cmd.Headers, "Authorization: Basic "+auth)
+	case "-A", "--user-agent":
+		p.cmd.Headers = append(p.cmd.Headers, "User-Agent: "+value)
+	case "-e", "--referer":
+		p.cmd.Headers = append(p.cmd.Headers, "Referer: "+value)
+	case "-x", "--proxy":
+		// Like curl, a proxy without a scheme is an HTTP proxy.
+		if !strings.Contains(value, "://") {
+			value = "http://" + value
+		}
+		p.cmd.Proxy = value
+	case "-U", "--proxy-user":
+		p.cmd.ProxyAuth = value
+	case "--resolve":
+		p.cmd.Resolve = append(p.cmd.Resolve, value)
+	case "--url":
+		p.cmd.URL = value
+	case "--unix-socket":
+		p.cmd.UnixSocket = value
+	case "--cacert":
+		p.cmd.CACert = value
+	case "-E", "--cert":
+		p.cmd.Cert = value
+	case "--key":
+		p.cmd.Key = value
+	case "-m", "--max-time":
+		d, err := seconds(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+		p.cmd.Timeout = d
+	case "--connect-timeout":
+		d, err := seconds(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+		p.cmd.ConnTimeout = d
+	}
+	return nil
+}
+
+// finish applies curl's defaults once every option has been seen.
+func (p *parser) finish() (*Command, error) {
+	cmd := p.cmd
+	if cmd.URL == "" {
+		return nil, fmt.Errorf("no URL in curl command")
+	}
+	if !strings.Contains(cmd.URL, "://") {
+		cmd.URL = "http://" + cmd.URL
+	}
+
+	if len(p.data) > 0 {
+		data := strings.Join(p.data, "&")
+		if p.getData {
+			sep := "?"
+			if strings.Contains(cmd.URL, "?") {
+				sep = "&"
+			}
+			cmd.URL += sep + data
+		} else {
+			cmd.Body = data
+			if cmd.Method == "" {
+				cmd.Method = "POST"
+			}
+			if p.jsonBody {
+				p.defaultHeader("Content-Type", "application/json")
+				p.defaultHeader("Accept", "application/json")
+			} else {
+				p.defaultHeader("Content-Type", "application/x-www-form-urlencoded")
+			}
+		}
+	}
+
+	if cmd.Method == "" {
+		cmd.Method = "GET"
+	}
+	return cmd, nil
+}
+
+// defaultHeader adds a header unless the command already sets it.
+func (p *parser) defaultHeader(name, value string) {
+	for _, h := range p.cmd.Headers {
+		if n, _, _ := strings.Cut(h, ":"); strings.EqualFold(strings.TrimSpace(n), name) {
+			return
+		}
+	}
+	p.cmd.Headers = append(p.cmd.Headers, name+": "+value)
+}
+
+// readData returns the data for -d style options, reading @file values.
+// Like curl, -d strips newlines from files while --data-binary does not.
+func readData(value string, stripNewlines bool) (string, error) {
+	if !strings.HasPrefix(value, "@") {
+		return value, nil
+	}
+	b, err := os.ReadFile(value[1:])
+	if err != nil {
+		return "", fmt.Errorf("reading data file: %w", err)
+	}
+	data := string(b)
+	if stripNewlines {
+		data = strings.NewReplacer("\r", "", "\n", "").Replace(data)
+	}
+	return data, nil
+}
+
+// urlencode implements the "content" and "name=content" forms of
+// --data-urlencode.
+func urlencode(value string) string {
+	if name, content, ok := strings.Cut(value, "="); ok {
+		if name == "" {
+			return url.QueryEscape(content)
+		}
+		return name + "=" + url.QueryEscape(content)
+	}
+	return url.QueryEscape(value)
+}
+
+// seconds parses curl's fractional seconds.
+func seconds(s string) (time.Duration, error) {
+	return time.ParseDuration(s + "s")
+}
+
+// Target returns the request as a scenario target.
+func (c *Command) Target() scenario.Target {
+	t := scenario.Target{
+		Method:  c.Method,
+		URL:     c.URL,
+		Body:    c.Body,
+		Cookies: c.Cookies,
+	}
+	header := make(http.Header)
+	for _, h := range c.Headers {
+		name, value, _ := strings.Cut(h, ":")
+		header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	// Scenarios hold one value per header, so repeated headers are joined
+	// as HTTP allows.
+	for name, values := range header {
+		if t.Headers == nil {
+			t.Headers = make(map[string]string)
+		}
+		t.Headers[name] = strings.Join(values, ", ")
+	}
+	return t
+}
+
+// RunFlags returns the BrickHauler flags for the connection-level options,
+// which have no place in a scenario file.
+func (c *Command) RunFlags() []string {
+	var flags []string
+	add := func(name, value string) {
+		if value != "" {
+			flags = append(flags, name, value)
+		}
+	}
+
+	if c.Insecure {
+		flags = append(flags, "--insecure")
+	}
+	for _, r := range c.Resolve {
+		add("--resolve", r)
+	}
+	add("--proxy", c.Proxy)
+	add("--proxy-auth", c.ProxyAuth)
+	add("--cacert", c.CACert)
+	add("--cert", c.Cert)
+	add("--key", c.Key)
+	add("--http-version", c.HTTPVersion)
+	add("--unix-socket", c.UnixSocket)
+	if c.Timeout > 0 {
+		add("--timeout", c.Timeout.String())
+	}
+	if c.ConnTimeout > 0 {
+		add("--connect-timeout", c.ConnTimeout.String())
+	}
+	return flags
+}
@@ -0,0 +1,248 @@
+package curl
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{`curl https://a.test`, []string{"curl", "https://a.test"}},
+		{`curl -H 'Accept: */*' "https://a.test/?q=a b"`, []string{"curl", "-H", "Accept: */*", "https://a.test/?q=a b"}},
+		{"curl 'https://a.test' \\\n  -H 'X: 1'", []string{"curl", "https://a.test", "-H", "X: 1"}},
+		{`curl --data-raw $'{"a":"it\'s\n"}'`, []string{"curl", "--data-raw", "{\"a\":\"it's\n\"}"}},
+		{`-d "say \"hi\""`, []string{"-d", `say "hi"`}},
+		{`a\ b`, []string{"a b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := split(tt.input)
+			if err != nil {
+				t.Fatalf("split(%q) unexpected error: %v", tt.input, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("split(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+
+	for _, bad := range []string{`'open`, `"open`, `$'open`, `trailing\`} {
+		if _, err := split(bad); err == nil {
+			t.Errorf("split(%q) expected error", bad)
+		}
+	}
+}
+
+func TestParse_DevtoolsCommand(t *testing.T) {
+	cmd, err := Parse(`curl 'https://api.test/v1/items' \
+  -H 'accept: application/json' \
+  -H 'authorization: Bearer abc' \
+  -b 'sid=123; theme=dark' \
+  --data-raw '{"name":"brick"}' \
+  --compressed`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cmd.Method != "POST" {
+		t.Errorf("Method = %q, want POST", cmd.Method)
+	}
+	if cmd.URL != "https://api.test/v1/items" {
+		t.Errorf("URL = %q", cmd.URL)
+	}
+	if cmd.Body != `{"name":"brick"}` {
+		t.Errorf("Body = %q", cmd.Body)
+	}
+	wantHeaders := []string{
+		"accept: application/json",
+		"authorization: Bearer abc",
+		"Content-Type: application/x-www-form-urlencoded",
+	}
+	if !reflect.DeepEqual(cmd.Headers, wantHeaders) {
+		t.Errorf("Headers = %q, want %q", cmd.Headers, wantHeaders)
+	}
+	if !reflect.DeepEqual(cmd.Cookies, []string{"sid=123", "theme=dark"}) {
+		t.Errorf("Cookies = %q", cmd.Cookies)
+	}
+}
+
+func TestParse_Options(t *testing.T) {
+	cmd, err := Parse(`curl -sSLk -XPUT -u alice:secret --resolve api.test:443:10.0.0.1 ` +
+		`-H 'Content-Type: text/plain' -d a=1 -d b=2 -m 2.5 --connect-timeout 1 ` +
+		`-x socks5h://gw:1080 -U bob:pw --http2 api.test/upload`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cmd.Method != "PUT" {
+		t.Errorf("Method = %q, want PUT", cmd.Method)
+	}
+	if cmd.URL != "http://api.test/upload" {
+		t.Errorf("URL = %q", cmd.URL)
+	}
+	if !cmd.Insecure {
+		t.Error("Insecure = false, want true")
+	}
+	if cmd.Body != "a=1&b=2" {
+		t.Errorf("Body = %q, want a=1&b=2", cmd.Body)
+	}
+	wantHeaders := []string{"Authorization: Basic YWxpY2U6c2VjcmV0", "Content-Type: text/plain"}
+	if !reflect.DeepEqual(cmd.Headers, wantHeaders) {
+		t.Errorf("Headers = %q, want %q", cmd.Headers, wantHeaders)
+	}
+	if !reflect.DeepEqual(cmd.Resolve, []string{"api.test:443:10.0.0.1"}) {
+		t.Errorf("Resolve = %q", cmd.Resolve)
+	}
+	if cmd.Timeout != 2500*time.Millisecond || cmd.ConnTimeout != time.Second {
+		t.Errorf("Timeout = %v, ConnTimeout = %v", cmd.Timeout, cmd.ConnTimeout)
+	}
+	if cmd.Proxy != "socks5h://gw:1080" || cmd.ProxyAuth != "bob:pw" {
+		t.Errorf("Proxy = %q, ProxyAuth = %q", cmd.Proxy, cmd.ProxyAuth)
+	}
+	if cmd.HTTPVersion != "2" {
+		t.Errorf("HTTPVersion = %q, want 2", cmd.HTTPVersion)
+	}
+}
+
+func TestParse_IgnoredOptionsWithValues(t *testing.T) {
+	cmd, err := Parse(`curl --max-redirs 5 --retry 3 --retry-delay 2 --limit-rate 100k ` +
+		`-o /dev/null -w '%{http_code}' -D headers.txt --compressed -L https://a.test/`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cmd.URL != "https://a.test/" || cmd.Method != "GET" {
+		t.Errorf("got %s %s, want GET https://a.test/", cmd.Method, cmd.URL)
+	}
+}
+
+func TestParse_BundledShortOptions(t *testing.T) {
+	tests := []struct {
+		input, method, body string
+	}{
+		{`curl -sXPOST -d x https://a.test`, "POST", "x"},
+		{`curl -sXPUT -d x https://a.test`, "PUT", "x"},
+		{`curl -sSX DELETE https://a.test`, "DELETE", ""},
+		{`curl -sd x=1 https://a.test`, "POST", "x=1"},
+		{`curl -kLsI https://a.test`, "HEAD", ""},
+	}
+	for _, tt := range tests {
+		cmd, err := Parse(tt.input)
+		if err != nil {
+			t.Errorf("Parse(%q) unexpected error: %v", tt.input, err)
+			continue
+		}
+		if cmd.Method != tt.method || cmd.Body != tt.body || cmd.URL != "https://a.test" {
+			t.Errorf("Parse(%q) = %s %s %q, want %s https://a.test %q", tt.input, cmd.Method, cmd.URL, cmd.Body, tt.method, tt.body)
+		}
+	}
+}
+
+func TestParse_ProxyWithoutScheme(t *testing.T) {
+	cmd, err := Parse(`curl -x proxy.local:3128 https://a.test`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cmd.Proxy != "http://proxy.local:3128" {
+		t.Errorf("Proxy = %q, want http://proxy.local:3128", cmd.Proxy)
+	}
+}
+
+func TestParse_DataFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "body.txt")
+	if err := os.WriteFile(path, []byte("line1\nline2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd, err := Parse(`curl --data-binary @` + path + ` https://a.test`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cmd.Body != "line1\nline2\n" {
+		t.Errorf("--data-binary Body = %q", cmd.Body)
+	}
+
+	cmd, err = Parse(`curl -d @` + path + ` https://a.test`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cmd.Body != "line1line2" {
+		t.Errorf("-d Body = %q", cmd.Body)
+	}
+
+	if _, err := Parse(`curl -d @missing.txt https://a.test`); err == nil {
+		t.Error("expected error for missing data file")
+	}
+}
+
+func TestParse_GetAndJSON(t *testing.T) {
+	cmd, err := Parse(`curl -G -d q=brick --data-urlencode 'tag=a b' 'https://a.test/search?page=1'`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cmd.Method != "GET" || cmd.Body != "" {
+		t.Errorf("Method = %q, Body = %q; want GET without body", cmd.Method, cmd.Body)
+	}
+	if cmd.URL != "https://a.test/search?page=1&q=brick&tag=a+b" {
+		t.Errorf("URL = %q", cmd.URL)
+	}
+
+	cmd, err = Parse(`curl --json '{"a":1}' https://a.test`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"Content-Type: application/json", "Accept: application/json"}
+	if cmd.Method != "POST" || !reflect.DeepEqual(cmd.Headers, want) {
+		t.Errorf("Method = %q, Headers = %q", cmd.Method, cmd.Headers)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []string{
+		`curl -H`,
+		`curl -X GET`,
+		`curl https://a.test https://b.test`,
+		`curl -b cookies.txt https://a.test`,
+		`curl -m soon https://a.test`,
+		`curl -F 'f=@x' https://a.test`,
+		`curl --form-string a=b https://a.test`,
+	}
+	for _, input := range tests {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("Parse(%q) expected error", input)
+		}
+	}
+}
+
+func TestCommand_TargetAndRunFlags(t *testing.T) {
+	cmd, err := Parse(`curl -k -b sid=1 -H 'x-trace: on' -H 'Accept: text/html' -H 'accept: */*' ` +
+		`--resolve a.test:443:10.0.0.1 -m 5 https://a.test/`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	target := cmd.Target()
+	if target.Method != "GET" || target.URL != "https://a.test/" {
+		t.Errorf("Target = %s %s", target.Method, target.URL)
+	}
+	if target.Headers["X-Trace"] != "on" {
+		t.Errorf("Headers = %v, want X-Trace: on", target.Headers)
+	}
+	if target.Headers["Accept"] != "text/html, */*" {
+		t.Errorf("Accept = %q, want both values", target.Headers["Accept"])
+	}
+	if !reflect.DeepEqual(target.Cookies, []string{"sid=1"}) {
+		t.Errorf("Cookies = %q", target.Cookies)
+	}
+
+	want := []string{"--insecure", "--resolve", "a.test:443:10.0.0.1", "--timeout", "5s"}
+	if got := cmd.RunFlags(); !reflect.DeepEqual(got, want) {
+		t.Errorf("RunFlags() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,125 @@
+package curl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// split breaks a command line into arguments following POSIX shell quoting:
+// single quotes, double quotes, backslash escapes, line continuations and
+// the $'...' form browsers use for bodies with special characters.
+func split(s string) ([]string, error) {
+	var (
+		args    []string
+		cur     strings.Builder
+		inToken bool
+	)
+
+	flush := func() {
+		if inToken {
+			args = append(args, cur.String())
+			cur.Reset()
+			inToken = false
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			flush()
+
+		case c == '\\':
+			if i+1 >= len(s) {
+				return nil, fmt.Errorf("trailing backslash in command")
+			}
+			i++
+			if s[i] == '\n' || (s[i] == '\r' && i+1 < len(s) && s[i+1] == '\n') {
+				// Line continuation.
+				if s[i] == '\r' {
+					i++
+				}
+				continue
+			}
+			cur.WriteByte(s[i])
+			inToken = true
+
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			cur.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inToken = true
+
+		case c == '$' && i+1 < len(s) && s[i+1] == '\'':
+			n, err := ansiQuoted(s[i+2:], &cur)
+			if err != nil {
+				return nil, err
+			}
+			i += n + 2
+			inToken = true
+
+		case c == '"':
+			n, err := doubleQuoted(s[i+1:], &cur)
+			if err != nil {
+				return nil, err
+			}
+			i += n + 1
+			inToken = true
+
+		default:
+			cur.WriteByte(c)
+			inToken = true
+		}
+	}
+	flush()
+
+	return args, nil
+}
+
+// doubleQuoted copies a "..." string body into b and returns the index of
+// the closing quote within s.
+func doubleQuoted(s string, b *strings.Builder) (int, error) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return i, nil
+		case '\\':
+			if i+1 < len(s) && strings.IndexByte("\"\\$`\n", s[i+1]) >= 0 {
+				i++
+				if s[i] != '\n' {
+					b.WriteByte(s[i])
+				}
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return 0, fmt.Errorf("unterminated double quote")
+}
+
+// ansiQuoted copies a $'...' string body into b, expanding escapes, and
+// returns the index of the closing quote within s.
+func ansiQuoted(s string, b *strings.Builder) (int, error) {
+	escapes := map[byte]byte{
+		'n': '\n', 't': '\t', 'r': '\r', '\\': '\\', '\'': '\'', '"': '"', '0': 0,
+	}
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\'':
+			return i, nil
+		case '\\':
+			if i+1 < len(s) {
+				if e, ok := escapes[s[i+1]]; ok {
+					b.WriteByte(e)
+					i++
+					continue
+				}
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return 0, fmt.Errorf("unterminated $' quote")
+}
@@ -0,0 +1,172 @@
+// Package har converts HTTP Archive (HAR) captures recorded by browsers
+// into BrickHauler scenarios.
+package har
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/EsteveSegura/BrickHauler/internal/scenario"
+)
+
+// StaticAssets matches URLs of images, stylesheets, scripts and fonts,
+// which are usually served by a CDN rather than the system under test.
+var StaticAssets = regexp.MustCompile(`(?i)\.(css|js|mjs|map|png|jpe?g|gif|webp|avif|svg|ico|woff2?|ttf|otf|eot)(\?|$)`)
+
+// File is the subset of the HAR 1.2 format needed to replay requests.
+type File struct {
+	Log struct {
+		Entries []Entry `json:"entries"`
+	} `json:"log"`
+}
+
+// Entry is a single request/response pair.
+type Entry struct {
+	StartedDateTime time.Time `json:"startedDateTime"`
+	// Time is the total elapsed time of the request in milliseconds.
+	Time    float64 `json:"time"`
+	Request Request `json:"request"`
+}
+
+// Request is the request half of an entry.
+type Request struct {
+	Method   string      `json:"method"`
+	URL      string      `json:"url"`
+	Headers  []NameValue `json:"headers"`
+	Cookies  []NameValue `json:"cookies"`
+	PostData *PostData   `json:"postData,omitempty"`
+}
+
+// NameValue is a header or cookie.
+type NameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// PostData is a request body.
+type PostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// Parse decodes a HAR document.
+func Parse(r io.Reader) (*File, error) {
+	var f File
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("decoding HAR: %w", err)
+	}
+	return &f, nil
+}
+
+// Options control the conversion to a scenario.
+type Options struct {
+	// Exclude drops requests whose URL matches any of the patterns.
+	Exclude []*regexp.Regexp
+}
+
+// skippedHeaders are managed by the HTTP client or carried elsewhere in
+// the scenario, so they are not copied from the capture.
+var skippedHeaders = map[string]bool{
+	"Host":              true,
+	"Content-Length":    true,
+	"Connection":        true,
+	"Cookie":            true,
+	"Transfer-Encoding": true,
+}
+
+// SkipHeader reports whether a captured header should be left out of a
+// scenario: HTTP/2 pseudo-headers and the headers the HTTP client manages
+// or the scenario carries elsewhere.
+func SkipHeader(name string) bool {
+	return strings.HasPrefix(name, ":") || skippedHeaders[http.CanonicalHeaderKey(name)]
+}
+
+// Scenario converts the captured entries into a sequential scenario that
+// replays the session in order, pausing between requests as the user did.
+func (f *File) Scenario(opts Options) (*scenario.Scenario, error) {
+	var entries []Entry
+	for _, e := range f.Log.Entries {
+		if !Excluded(e.Request.URL, opts.Exclude) {
+			entries = append(entries, e)
+		}
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no requests left to import")
+	}
+
+	s := &scenario.Scenario{Mode: scenario.ModeSequence}
+	for i, e := range entries {
+		t := scenario.Target{
+			Method: strings.ToUpper(e.Request.Method),
+			URL:    e.Request.URL,
+		}
+
+		for _, h := range e.Request.Headers {
+			if SkipHeader(h.Name) {
+				continue
+			}
+			name := http.CanonicalHeaderKey(h.Name)
+			if t.Headers == nil {
+				t.Headers = make(map[string]string)
+			}
+			// Scenarios hold one value per header, so repeated headers are
+			// joined as HTTP allows.
+			if prev, ok := t.Headers[name]; ok {
+				t.Headers[name] = prev + ", " + h.Value
+				continue
+			}
+			t.Headers[name] = h.Value
+		}
+
+		for _, c := range e.Request.Cookies {
+			// A cookie without a name would stop the scenario from loading.
+			if c.Name == "" {
+				continue
+			}
+			t.Cookies = append(t.Cookies, c.Name+"="+c.Value)
+		}
+
+		if pd := e.Request.PostData; pd != nil && pd.Text != "" {
+			t.Body = pd.Text
+			if _, ok := t.Headers["Content-Type"]; !ok && pd.MimeType != "" {
+				if t.Headers == nil {
+					t.Headers = make(map[string]string)
+				}
+				t.Headers["Content-Type"] = pd.MimeType
+			}
+		}
+
+		if i+1 < len(entries) {
+			t.ThinkTime = scenario.Duration(thinkTime(e, entries[i+1]))
+		}
+
+		s.Targets = append(s.Targets, t)
+	}
+	return s, nil
+}
+
+// thinkTime is the pause between the end of one request and the start of
+// the next, rounded to milliseconds.
+func thinkTime(cur, next Entry) time.Duration {
+	end := cur.StartedDateTime.Add(time.Duration(cur.Time * float64(time.Millisecond)))
+	gap := next.StartedDateTime.Sub(end)
+	if gap < 0 {
+		return 0
+	}
+	return gap.Round(time.Millisecond)
+}
+
+// Excluded reports whether url matches any of the patterns.
+func Excluded(url string, patterns []*regexp.Regexp) bool {
+	for _, p := range patterns {
+		if p.MatchString(url) {
+			return true
+		}
+	}
+	return false
+}
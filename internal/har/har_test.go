@@ -0,0 +1,137 @@
+package har
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EsteveSegura/BrickHauler/internal/scenario"
+)
+
+const capture = `{
+  "log": {
+    "version": "1.2",
+    "entries": [
+      {
+        "startedDateTime": "2024-05-01T10:00:00.000Z",
+        "time": 120,
+        "request": {
+          "method": "GET",
+          "url": "https://shop.example/",
+          "headers": [
+            {"name": ":authority", "value": "shop.example"},
+            {"name": "accept", "value": "text/html"},
+            {"name": "Accept", "value": "application/xhtml+xml"},
+            {"name": "cookie", "value": "sid=abc"}
+          ],
+          "cookies": [{"name": "sid", "value": "abc"}, {"name": "", "value": "orphan"}]
+        }
+      },
+      {
+        "startedDateTime": "2024-05-01T10:00:00.200Z",
+        "time": 10,
+        "request": {"method": "GET", "url": "https://cdn.shop.example/app.js?v=3", "headers": [], "cookies": []}
+      },
+      {
+        "startedDateTime": "2024-05-01T10:00:02.120Z",
+        "time": 80,
+        "request": {
+          "method": "post",
+          "url": "https://shop.example/api/cart",
+          "headers": [{"name": "Content-Length", "value": "9"}],
+          "cookies": [],
+          "postData": {"mimeType": "application/json", "text": "{\"id\":42}"}
+        }
+      },
+      {
+        "startedDateTime": "2024-05-01T10:00:02.150Z",
+        "time": 30,
+        "request": {"method": "GET", "url": "https://shop.example/analytics/beacon", "headers": [], "cookies": []}
+      }
+    ]
+  }
+}`
+
+func TestScenario(t *testing.T) {
+	f, err := Parse(strings.NewReader(capture))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sc, err := f.Scenario(Options{Exclude: []*regexp.Regexp{StaticAssets, regexp.MustCompile(`/analytics/`)}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if sc.Mode != scenario.ModeSequence {
+		t.Errorf("Mode = %q, want %q", sc.Mode, scenario.ModeSequence)
+	}
+	if len(sc.Targets) != 2 {
+		t.Fatalf("got %d targets, want 2 (static and analytics excluded)", len(sc.Targets))
+	}
+
+	home, cart := sc.Targets[0], sc.Targets[1]
+
+	if home.Headers["Accept"] != "text/html, application/xhtml+xml" {
+		t.Errorf("home Accept header = %q", home.Headers["Accept"])
+	}
+	if _, ok := home.Headers["Cookie"]; ok {
+		t.Error("Cookie header should be carried as cookies, not a header")
+	}
+	if len(home.Cookies) != 1 || home.Cookies[0] != "sid=abc" {
+		t.Errorf("home cookies = %v, want only sid=abc", home.Cookies)
+	}
+	// Home ends at 10:00:00.120 and the cart request starts at 10:00:02.120.
+	if time.Duration(home.ThinkTime) != 2*time.Second {
+		t.Errorf("home think time = %v, want 2s", time.Duration(home.ThinkTime))
+	}
+
+	if cart.Method != "POST" || cart.Body != `{"id":42}` {
+		t.Errorf("cart = %+v", cart)
+	}
+	if cart.Headers["Content-Type"] != "application/json" {
+		t.Errorf("cart Content-Type = %q", cart.Headers["Content-Type"])
+	}
+	if _, ok := cart.Headers["Content-Length"]; ok {
+		t.Error("Content-Length should not be copied")
+	}
+	if cart.ThinkTime != 0 {
+		t.Errorf("last request think time = %v, want 0", time.Duration(cart.ThinkTime))
+	}
+
+	if _, err := sc.ConfigTargets(); err != nil {
+		t.Errorf("imported scenario does not load: %v", err)
+	}
+}
+
+func TestScenario_NothingLeft(t *testing.T) {
+	f, err := Parse(strings.NewReader(capture))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := f.Scenario(Options{Exclude: []*regexp.Regexp{regexp.MustCompile(`.`)}}); err == nil {
+		t.Error("expected error when every request is excluded")
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	if _, err := Parse(strings.NewReader(`{"log": `)); err == nil {
+		t.Error("expected error for malformed HAR")
+	}
+}
+
+func TestSkipHeader(t *testing.T) {
+	for name, want := range map[string]bool{
+		":authority":     true,
+		"host":           true,
+		"Cookie":         true,
+		"content-length": true,
+		"Accept":         false,
+		"X-Request-Id":   false,
+	} {
+		if got := SkipHeader(name); got != want {
+			t.Errorf("SkipHeader(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
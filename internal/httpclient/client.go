@@ -1,37 +1,120 @@
 package httpclient
 
 import (
+	"crypto/tls"
+	"net"
 	"net/http"
-	"net/url"
 	"time"
 )
 
+// IdleConnTimeout is how long an unused connection is kept for reuse.
+const IdleConnTimeout = 90 * time.Second
+
 // Config for HTTP client creation.
 type Config struct {
-	ProxyURL *url.URL
-	Timeout  time.Duration
+	Timeout               time.Duration
+	ConnectTimeout        time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	MaxIdleConns          int
+	MaxConnsPerHost       int
+	DisableKeepAlives     bool
+	TLSConfig             *tls.Config
+	DNSCacheTTL           time.Duration
+	Resolve               *ResolveTable
+	UnixSocket            string
+	// SourceIP binds outgoing TCP connections to this local address.
+	SourceIP net.IP
+
+	// HTTPVersion is "1.1", "2", "h2c" or empty to negotiate via ALPN.
+	HTTPVersion               string
+	HTTP2MaxReadFrame         int
+	HTTP2MaxReceiveBufStream  int
+	HTTP2MaxReceiveBufConnect int
 }
 
-// New creates a configured HTTP client with connection pooling.
+// New creates a configured HTTP client with connection pooling. Every
+// client owns its connections and TLS session cache, plus its own DNS
+// cache when DNSCacheTTL is set.
 func New(cfg Config) *http.Client {
-	transport := &http.Transport{
-		MaxIdleConns:        100,
-		MaxIdleConnsPerHost: 100,
-		MaxConnsPerHost:     100,
-		IdleConnTimeout:     90 * time.Second,
+	d := &dialer{
+		net: &net.Dialer{
+			Timeout:   orDefault(cfg.ConnectTimeout, 30*time.Second),
+			KeepAlive: 30 * time.Second,
+			LocalAddr: localAddr(cfg.SourceIP),
+		},
+		resolve: cfg.Resolve,
+		socket:  cfg.UnixSocket,
+	}
+	if cfg.DNSCacheTTL > 0 {
+		d.dns = newDNSCache(cfg.DNSCacheTTL)
 	}
 
-	if cfg.ProxyURL != nil {
-		transport.Proxy = http.ProxyURL(cfg.ProxyURL)
+	tlsConfig := &tls.Config{}
+	if cfg.TLSConfig != nil {
+		tlsConfig = cfg.TLSConfig.Clone()
+	}
+	tlsConfig.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+
+	maxIdle := cfg.MaxIdleConns
+	if maxIdle == 0 {
+		maxIdle = 100
+	}
+
+	transport := &http.Transport{
+		DialContext:           d.DialContext,
+		MaxIdleConns:          maxIdle,
+		MaxIdleConnsPerHost:   maxIdle,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		IdleConnTimeout:       IdleConnTimeout,
+		TLSHandshakeTimeout:   orDefault(cfg.TLSHandshakeTimeout, 10*time.Second),
+		ResponseHeaderTimeout: cfg.ResponseHeaderTimeout,
+		DisableKeepAlives:     cfg.DisableKeepAlives,
+		TLSClientConfig:       tlsConfig,
+		Proxy:                 proxyFor,
 	}
 
-	timeout := cfg.Timeout
-	if timeout == 0 {
-		timeout = 30 * time.Second
+	transport.Protocols = protocols(cfg.HTTPVersion)
+	transport.HTTP2 = &http.HTTP2Config{
+		MaxReadFrameSize:              cfg.HTTP2MaxReadFrame,
+		MaxReceiveBufferPerStream:     cfg.HTTP2MaxReceiveBufStream,
+		MaxReceiveBufferPerConnection: cfg.HTTP2MaxReceiveBufConnect,
 	}
 
 	return &http.Client{
 		Transport: transport,
-		Timeout:   timeout,
+		Timeout:   orDefault(cfg.Timeout, 30*time.Second),
+	}
+}
+
+// localAddr returns the TCP address to bind to, or nil for any.
+func localAddr(ip net.IP) net.Addr {
+	if ip == nil {
+		return nil
+	}
+	return &net.TCPAddr{IP: ip}
+}
+
+// protocols returns the protocol set for the requested HTTP version.
+func protocols(version string) *http.Protocols {
+	p := new(http.Protocols)
+	switch version {
+	case "1.1":
+		p.SetHTTP1(true)
+	case "2":
+		p.SetHTTP2(true)
+	case "h2c":
+		p.SetUnencryptedHTTP2(true)
+	default:
+		p.SetHTTP1(true)
+		p.SetHTTP2(true)
+	}
+	return p
+}
+
+func orDefault(d, def time.Duration) time.Duration {
+	if d == 0 {
+		return def
 	}
+	return d
 }
@@ -0,0 +1,105 @@
+package httpclient
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// dialer opens TCP connections, honoring pinned addresses and optionally
+// resolving host names through a cache owned by the client. When socket is
+// set every connection goes to that unix socket instead.
+type dialer struct {
+	net     *net.Dialer
+	dns     *dnsCache
+	resolve *ResolveTable
+	socket  string
+}
+
+// DialContext connects to addr, trying each resolved address in turn.
+func (d *dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if d.socket != "" {
+		unix := &net.Dialer{Timeout: d.net.Timeout}
+		return unix.DialContext(ctx, "unix", d.socket)
+	}
+
+	if pinned, ok := d.resolve.lookup(addr); ok {
+		return d.net.DialContext(ctx, network, pinned)
+	}
+
+	if d.dns == nil {
+		return d.net.DialContext(ctx, network, addr)
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) != nil {
+		return d.net.DialContext(ctx, network, addr)
+	}
+
+	ips, err := d.dns.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.dialFirst(ctx, network, ips, port)
+}
+
+// dialFirst returns the first successful connection among ips.
+func (d *dialer) dialFirst(ctx context.Context, network string, ips []string, port string) (net.Conn, error) {
+	var firstErr error
+	for _, ip := range ips {
+		conn, err := d.net.DialContext(ctx, network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+// dnsCache remembers host name lookups for a fixed time.
+type dnsCache struct {
+	ttl      time.Duration
+	resolver *net.Resolver
+
+	mu      sync.Mutex
+	entries map[string]dnsEntry
+}
+
+type dnsEntry struct {
+	ips     []string
+	expires time.Time
+}
+
+func newDNSCache(ttl time.Duration) *dnsCache {
+	return &dnsCache{
+		ttl:      ttl,
+		resolver: net.DefaultResolver,
+		entries:  make(map[string]dnsEntry),
+	}
+}
+
+// lookup returns the cached addresses for host, resolving them on a miss.
+func (c *dnsCache) lookup(ctx context.Context, host string) ([]string, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[host]
+	c.mu.Unlock()
+
+	if ok && time.Now().Before(entry.expires) {
+		return entry.ips, nil
+	}
+
+	ips, err := c.resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[host] = dnsEntry{ips: ips, expires: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+
+	return ips, nil
+}
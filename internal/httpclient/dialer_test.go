@@ -0,0 +1,67 @@
+package httpclient
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDNSCache_Lookup(t *testing.T) {
+	c := newDNSCache(time.Minute)
+
+	ips, err := c.lookup(context.Background(), "localhost")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ips) == 0 {
+		t.Fatal("lookup(localhost) returned no addresses")
+	}
+
+	// A cached entry is served without resolving again.
+	c.entries["cached.invalid"] = dnsEntry{ips: []string{"10.0.0.1"}, expires: time.Now().Add(time.Minute)}
+	ips, err = c.lookup(context.Background(), "cached.invalid")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ips) != 1 || ips[0] != "10.0.0.1" {
+		t.Errorf("lookup(cached.invalid) = %v, want [10.0.0.1]", ips)
+	}
+}
+
+func TestDNSCache_Expired(t *testing.T) {
+	c := newDNSCache(time.Minute)
+	c.entries["localhost"] = dnsEntry{ips: []string{"10.0.0.1"}, expires: time.Now().Add(-time.Second)}
+
+	ips, err := c.lookup(context.Background(), "localhost")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, ip := range ips {
+		if ip == "10.0.0.1" {
+			t.Errorf("lookup returned expired entry %v", ips)
+		}
+	}
+}
+
+func TestResolveTable_RoundRobin(t *testing.T) {
+	rt := NewResolveTable(map[string][]string{
+		"api.test:443": {"10.0.0.1", "10.0.0.2"},
+	})
+
+	want := []string{"10.0.0.1:443", "10.0.0.2:443", "10.0.0.1:443"}
+	for i, w := range want {
+		got, ok := rt.lookup("API.test:443")
+		if !ok || got != w {
+			t.Errorf("lookup #%d = %q, %v; want %q", i, got, ok, w)
+		}
+	}
+
+	if _, ok := rt.lookup("other.test:443"); ok {
+		t.Error("lookup of unmapped host should not match")
+	}
+
+	var empty *ResolveTable
+	if _, ok := empty.lookup("api.test:443"); ok {
+		t.Error("nil table should not match")
+	}
+}
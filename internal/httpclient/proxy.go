@@ -0,0 +1,26 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+type proxyKey struct{}
+
+// WithProxy returns a context that routes requests made with it through
+// the given HTTP(S) or SOCKS5 proxy.
+func WithProxy(ctx context.Context, proxy *url.URL) context.Context {
+	return context.WithValue(ctx, proxyKey{}, proxy)
+}
+
+// proxyFor picks the proxy stored in the request context, falling back to
+// the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables. Proxy
+// credentials in the URL are sent as Proxy-Authorization for HTTP proxies
+// and as username/password authentication for SOCKS5.
+func proxyFor(req *http.Request) (*url.URL, error) {
+	if proxy, ok := req.Context().Value(proxyKey{}).(*url.URL); ok && proxy != nil {
+		return proxy, nil
+	}
+	return http.ProxyFromEnvironment(req)
+}
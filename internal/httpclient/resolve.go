@@ -0,0 +1,50 @@
+package httpclient
+
+import (
+	"net"
+	"strings"
+	"sync/atomic"
+)
+
+// ResolveTable pins "host:port" pairs to fixed IP addresses, bypassing DNS
+// while the Host header and SNI keep the original name. When a pair maps to
+// several addresses, new connections rotate through them. A table can be
+// shared by several clients so the rotation is global.
+type ResolveTable struct {
+	entries map[string]*resolveEntry
+}
+
+type resolveEntry struct {
+	addrs []string
+	next  atomic.Uint64
+}
+
+// NewResolveTable creates a table from "host:port" keys to IP addresses.
+// It returns nil for an empty mapping.
+func NewResolveTable(m map[string][]string) *ResolveTable {
+	if len(m) == 0 {
+		return nil
+	}
+
+	t := &ResolveTable{entries: make(map[string]*resolveEntry, len(m))}
+	for key, addrs := range m {
+		t.entries[key] = &resolveEntry{addrs: addrs}
+	}
+	return t
+}
+
+// lookup returns the pinned address for addr, rotating between candidates.
+func (t *ResolveTable) lookup(addr string) (string, bool) {
+	if t == nil {
+		return "", false
+	}
+
+	e, ok := t.entries[strings.ToLower(addr)]
+	if !ok {
+		return "", false
+	}
+
+	_, port, _ := net.SplitHostPort(addr)
+	i := e.next.Add(1) - 1
+	return net.JoinHostPort(e.addrs[i%uint64(len(e.addrs))], port), true
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import "sync"
+
+// Group holds one Metrics collector per key, such as per proxy or per
+// target, created on first use.
+type Group struct {
+	mu      sync.Mutex
+	members map[string]*Metrics
+}
+
+// NewGroup creates an empty Group.
+func NewGroup() *Group {
+	return &Group{members: make(map[string]*Metrics)}
+}
+
+// Get returns the collector for key, creating it if needed.
+func (g *Group) Get(key string) *Metrics {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	m, ok := g.members[key]
+	if !ok {
+		m = New(0)
+		g.members[key] = m
+	}
+	return m
+}
+
+// Snapshot returns a snapshot of every collector in the group.
+func (g *Group) Snapshot() map[string]Snapshot {
+	g.mu.Lock()
+	members := make(map[string]*Metrics, len(g.members))
+	for k, m := range g.members {
+		members[k] = m
+	}
+	g.mu.Unlock()
+
+	out := make(map[string]Snapshot, len(members))
+	for k, m := range members {
+		out[k] = m.Snapshot()
+	}
+	return out
+}
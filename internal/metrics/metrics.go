@@ -15,6 +15,10 @@ type Metrics struct {
 
 	mu        sync.Mutex
 	durations []time.Duration
+
+	protocols    counter
+	tlsVersions  counter
+	cipherSuites counter
 }
 
 // New creates a new Metrics collector with pre-allocated capacity.
@@ -39,12 +43,26 @@ func (m *Metrics) RecordFailure() {
 	m.failureCount.Add(1)
 }
 
+// RecordProtocol records the HTTP protocol negotiated for a request.
+func (m *Metrics) RecordProtocol(proto string) {
+	m.protocols.inc(proto)
+}
+
+// RecordTLS records the TLS version and cipher suite negotiated for a request.
+func (m *Metrics) RecordTLS(version, cipherSuite string) {
+	m.tlsVersions.inc(version)
+	m.cipherSuites.inc(cipherSuite)
+}
+
 // Snapshot represents a point-in-time copy of metrics.
 type Snapshot struct {
 	SuccessCount int64
 	FailureCount int64
 	TotalTime    time.Duration
 	Durations    []time.Duration // sorted
+	Protocols    map[string]int64
+	TLSVersions  map[string]int64
+	CipherSuites map[string]int64
 }
 
 // Snapshot returns a copy of current metrics for reporting.
@@ -63,6 +81,9 @@ func (m *Metrics) Snapshot() Snapshot {
 		FailureCount: m.failureCount.Load(),
 		TotalTime:    time.Duration(m.totalTime.Load()),
 		Durations:    durations,
+		Protocols:    m.protocols.snapshot(),
+		TLSVersions:  m.tlsVersions.snapshot(),
+		CipherSuites: m.cipherSuites.snapshot(),
 	}
 }
 
@@ -90,3 +111,29 @@ func (s Snapshot) AverageTime() time.Duration {
 	}
 	return s.TotalTime / time.Duration(s.SuccessCount)
 }
+
+// counter counts occurrences of string keys in a thread-safe manner.
+type counter struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func (c *counter) inc(key string) {
+	c.mu.Lock()
+	if c.counts == nil {
+		c.counts = make(map[string]int64)
+	}
+	c.counts[key]++
+	c.mu.Unlock()
+}
+
+func (c *counter) snapshot() map[string]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := make(map[string]int64, len(c.counts))
+	for k, v := range c.counts {
+		out[k] = v
+	}
+	return out
+}
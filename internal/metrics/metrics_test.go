@@ -186,3 +186,38 @@ func TestSnapshot_DurationsAreSorted(t *testing.T) {
 		}
 	}
 }
+
+func TestMetrics_RecordTLS(t *testing.T) {
+	m := New(2)
+
+	m.RecordTLS("TLS 1.3", "TLS_AES_128_GCM_SHA256")
+	m.RecordTLS("TLS 1.3", "TLS_AES_128_GCM_SHA256")
+	m.RecordTLS("TLS 1.2", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256")
+
+	snap := m.Snapshot()
+	if snap.TLSVersions["TLS 1.3"] != 2 {
+		t.Errorf("TLSVersions[TLS 1.3] = %d, want 2", snap.TLSVersions["TLS 1.3"])
+	}
+	if snap.CipherSuites["TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"] != 1 {
+		t.Errorf("CipherSuites = %v", snap.CipherSuites)
+	}
+}
+
+func TestGroup(t *testing.T) {
+	g := NewGroup()
+
+	g.Get("a").RecordSuccess(10 * time.Millisecond)
+	g.Get("a").RecordFailure()
+	g.Get("b").RecordSuccess(20 * time.Millisecond)
+
+	snaps := g.Snapshot()
+	if len(snaps) != 2 {
+		t.Fatalf("len(Snapshot()) = %d, want 2", len(snaps))
+	}
+	if snaps["a"].SuccessCount != 1 || snaps["a"].FailureCount != 1 {
+		t.Errorf("snaps[a] = %+v", snaps["a"])
+	}
+	if snaps["b"].TotalRequests() != 1 {
+		t.Errorf("snaps[b].TotalRequests() = %d, want 1", snaps["b"].TotalRequests())
+	}
+}
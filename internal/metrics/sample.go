@@ -0,0 +1,47 @@
+package metrics
+
+import "time"
+
+// Error classes of failed requests.
+const (
+	ErrorNone     = ""
+	ErrorHTTP     = "http_status"
+	ErrorTimeout  = "timeout"
+	ErrorDNS      = "dns"
+	ErrorRefused  = "connection_refused"
+	ErrorReset    = "connection_reset"
+	ErrorTLS      = "tls"
+	ErrorCanceled = "canceled"
+	ErrorOther    = "other"
+)
+
+// Sample describes one measured request as it completes.
+type Sample struct {
+	// Target names the request template, or the path template of a
+	// replayed request.
+	Target string
+	Method string
+	// Status is the response status code, zero when no response came back.
+	Status int
+	// Error classifies a failed request, ErrorNone for a successful one.
+	Error    string
+	Duration time.Duration
+}
+
+// Failed reports whether the request counts as a failure.
+func (s Sample) Failed() bool {
+	return s.Error != ErrorNone
+}
+
+// Observer is told about every measured request, from many goroutines at
+// once.
+type Observer interface {
+	Observe(Sample)
+}
+
+// StartObserver is an Observer that is also told when the measured run
+// starts, after any warm-up.
+type StartObserver interface {
+	Observer
+	Start()
+}
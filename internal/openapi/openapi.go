@@ -0,0 +1,404 @@
+// Package openapi turns OpenAPI 3 documents into BrickHauler scenarios,
+// generating example parameters and bodies from the operation schemas.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/EsteveSegura/BrickHauler/internal/scenario"
+)
+
+// Document is the subset of an OpenAPI 3 document needed to build
+// requests. Only the JSON encoding is supported.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Servers    []Server            `json:"servers"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Server is a base URL, possibly with {variables}.
+type Server struct {
+	URL       string                    `json:"url"`
+	Variables map[string]ServerVariable `json:"variables"`
+}
+
+// ServerVariable is a substitution for a server URL template.
+type ServerVariable struct {
+	Default string `json:"default"`
+}
+
+// PathItem holds the operations available on one path.
+type PathItem struct {
+	Parameters []Parameter `json:"parameters"`
+	Get        *Operation  `json:"get"`
+	Put        *Operation  `json:"put"`
+	Post       *Operation  `json:"post"`
+	Delete     *Operation  `json:"delete"`
+	Options    *Operation  `json:"options"`
+	Head       *Operation  `json:"head"`
+	Patch      *Operation  `json:"patch"`
+}
+
+// Operation is a single API operation.
+type Operation struct {
+	OperationID string       `json:"operationId"`
+	Tags        []string     `json:"tags"`
+	Parameters  []Parameter  `json:"parameters"`
+	RequestBody *RequestBody `json:"requestBody"`
+}
+
+// Parameter is a path, query, header or cookie parameter.
+type Parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+	Example  any     `json:"example"`
+}
+
+// RequestBody describes the accepted request bodies by media type.
+type RequestBody struct {
+	Ref     string               `json:"$ref"`
+	Content map[string]MediaType `json:"content"`
+}
+
+// MediaType is the schema and examples for one content type.
+type MediaType struct {
+	Schema   *Schema            `json:"schema"`
+	Example  any                `json:"example"`
+	Examples map[string]Example `json:"examples"`
+}
+
+// Example is a named example value.
+type Example struct {
+	Value any `json:"value"`
+}
+
+// Components holds the reusable objects that $ref can point to.
+type Components struct {
+	Schemas       map[string]*Schema     `json:"schemas"`
+	Parameters    map[string]Parameter   `json:"parameters"`
+	RequestBodies map[string]RequestBody `json:"requestBodies"`
+}
+
+// Parse decodes an OpenAPI 3 document.
+func Parse(r io.Reader) (*Document, error) {
+	var d Document
+	if err := json.NewDecoder(r).Decode(&d); err != nil {
+		return nil, fmt.Errorf("decoding OpenAPI document (only JSON is supported): %w", err)
+	}
+	if !strings.HasPrefix(d.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %q: must be 3.x", d.OpenAPI)
+	}
+	return &d, nil
+}
+
+// Options control which operations are imported and where they are sent.
+type Options struct {
+	// Server overrides the base URL from the document.
+	Server string
+	// Operations keeps only these operations, given by operationId or
+	// as "METHOD /path".
+	Operations []string
+	// Tags keeps only operations with one of these tags.
+	Tags []string
+	// Exclude drops operations whose "METHOD /path" matches any pattern.
+	Exclude []*regexp.Regexp
+}
+
+// method is one operation of a path item.
+type method struct {
+	name string
+	op   *Operation
+}
+
+// methods lists the operations of a path item in a stable order.
+func (p PathItem) methods() []method {
+	all := []method{
+		{http.MethodGet, p.Get},
+		{http.MethodPost, p.Post},
+		{http.MethodPut, p.Put},
+		{http.MethodPatch, p.Patch},
+		{http.MethodDelete, p.Delete},
+		{http.MethodHead, p.Head},
+		{http.MethodOptions, p.Options},
+	}
+	return slices.DeleteFunc(all, func(m method) bool { return m.op == nil })
+}
+
+// Scenario builds a weighted scenario with one target per selected
+// operation. Targets are named after the operation so the results are
+// broken down per operation.
+func (d *Document) Scenario(opts Options) (*scenario.Scenario, error) {
+	base, err := d.baseURL(opts.Server)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(d.Paths))
+	for p := range d.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	s := &scenario.Scenario{Mode: scenario.ModeWeighted}
+	for _, path := range paths {
+		item := d.Paths[path]
+		for _, m := range item.methods() {
+			key := m.name + " " + path
+			if !selected(m.op, key, opts) {
+				continue
+			}
+			t, err := d.target(base, path, m.name, item, m.op)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			s.Targets = append(s.Targets, t)
+		}
+	}
+	if len(s.Targets) == 0 {
+		return nil, fmt.Errorf("no operations left to import")
+	}
+	return s, nil
+}
+
+func selected(op *Operation, key string, opts Options) bool {
+	for _, re := range opts.Exclude {
+		if re.MatchString(key) {
+			return false
+		}
+	}
+	if len(opts.Operations) > 0 && !slices.ContainsFunc(opts.Operations, func(o string) bool {
+		return o == op.OperationID || strings.EqualFold(o, key)
+	}) {
+		return false
+	}
+	if len(opts.Tags) > 0 && !slices.ContainsFunc(op.Tags, func(t string) bool {
+		return slices.Contains(opts.Tags, t)
+	}) {
+		return false
+	}
+	return true
+}
+
+// baseURL returns the server URL without a trailing slash.
+func (d *Document) baseURL(override string) (string, error) {
+	raw := override
+	if raw == "" {
+		if len(d.Servers) == 0 {
+			return "", fmt.Errorf("document has no servers: give the base URL explicitly")
+		}
+		srv := d.Servers[0]
+		raw = srv.URL
+		for name, v := range srv.Variables {
+			raw = strings.ReplaceAll(raw, "{"+name+"}", v.Default)
+		}
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid server URL %q: %w", raw, err)
+	}
+	if !u.IsAbs() {
+		return "", fmt.Errorf("server URL %q is relative: give the base URL explicitly", raw)
+	}
+	return strings.TrimSuffix(raw, "/"), nil
+}
+
+func (d *Document) target(base, path, verb string, item PathItem, op *Operation) (scenario.Target, error) {
+	name := op.OperationID
+	if name == "" {
+		name = verb + " " + path
+	}
+	t := scenario.Target{Name: name, Method: verb}
+
+	params, err := d.parameters(item.Parameters, op.Parameters)
+	if err != nil {
+		return t, err
+	}
+
+	query := url.Values{}
+	for _, p := range params {
+		// Optional parameters are only sent when the spec gives an example.
+		if !p.Required && p.In != "path" && p.Example == nil && (p.Schema == nil || p.Schema.Example == nil) {
+			continue
+		}
+		value := formatValue(d.paramValue(p))
+		switch p.In {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+p.Name+"}", url.PathEscape(value))
+		case "query":
+			query.Add(p.Name, value)
+		case "header":
+			if t.Headers == nil {
+				t.Headers = make(map[string]string)
+			}
+			t.Headers[http.CanonicalHeaderKey(p.Name)] = value
+		case "cookie":
+			t.Cookies = append(t.Cookies, p.Name+"="+value)
+		}
+	}
+	if strings.Contains(path, "{") {
+		return t, fmt.Errorf("path %s has undeclared parameters", path)
+	}
+
+	t.URL = base + path
+	if len(query) > 0 {
+		t.URL += "?" + query.Encode()
+	}
+
+	if op.RequestBody != nil {
+		contentType, body, err := d.body(op.RequestBody)
+		if err != nil {
+			return t, err
+		}
+		if contentType != "" {
+			if t.Headers == nil {
+				t.Headers = make(map[string]string)
+			}
+			t.Headers["Content-Type"] = contentType
+			t.Body = body
+		}
+	}
+	return t, nil
+}
+
+// parameters merges path-level and operation-level parameters, resolving
+// references. Operation parameters override path ones with the same name
+// and location.
+func (d *Document) parameters(pathParams, opParams []Parameter) ([]Parameter, error) {
+	var out []Parameter
+	for _, p := range slices.Concat(pathParams, opParams) {
+		p, err := d.resolveParameter(p)
+		if err != nil {
+			return nil, err
+		}
+		out = slices.DeleteFunc(out, func(q Parameter) bool {
+			return q.Name == p.Name && q.In == p.In
+		})
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func (d *Document) resolveParameter(p Parameter) (Parameter, error) {
+	if p.Ref == "" {
+		return p, nil
+	}
+	name, ok := strings.CutPrefix(p.Ref, "#/components/parameters/")
+	if !ok {
+		return p, fmt.Errorf("unsupported reference %q", p.Ref)
+	}
+	resolved, ok := d.Components.Parameters[name]
+	if !ok {
+		return p, fmt.Errorf("unknown parameter %q", p.Ref)
+	}
+	return resolved, nil
+}
+
+func (d *Document) paramValue(p Parameter) any {
+	if p.Example != nil {
+		return p.Example
+	}
+	return d.example(p.Schema, 0)
+}
+
+// body picks a media type and renders an example body for it. JSON is
+// preferred, then form encoding, then any type that carries an example.
+func (d *Document) body(rb *RequestBody) (string, string, error) {
+	if rb.Ref != "" {
+		name, ok := strings.CutPrefix(rb.Ref, "#/components/requestBodies/")
+		if !ok {
+			return "", "", fmt.Errorf("unsupported reference %q", rb.Ref)
+		}
+		resolved, ok := d.Components.RequestBodies[name]
+		if !ok {
+			return "", "", fmt.Errorf("unknown request body %q", rb.Ref)
+		}
+		rb = &resolved
+	}
+
+	types := make([]string, 0, len(rb.Content))
+	for ct := range rb.Content {
+		types = append(types, ct)
+	}
+	sort.Strings(types)
+
+	for _, ct := range types {
+		if isJSON(ct) {
+			b, err := json.Marshal(d.mediaExample(rb.Content[ct]))
+			if err != nil {
+				return "", "", fmt.Errorf("encoding example body: %w", err)
+			}
+			return ct, string(b), nil
+		}
+	}
+	if mt, ok := rb.Content["application/x-www-form-urlencoded"]; ok {
+		form := url.Values{}
+		if obj, ok := d.mediaExample(mt).(map[string]any); ok {
+			for k, v := range obj {
+				form.Set(k, formatValue(v))
+			}
+		}
+		return "application/x-www-form-urlencoded", form.Encode(), nil
+	}
+	for _, ct := range types {
+		if s, ok := d.mediaExample(rb.Content[ct]).(string); ok {
+			return ct, s, nil
+		}
+	}
+	return "", "", nil
+}
+
+func (d *Document) mediaExample(mt MediaType) any {
+	if mt.Example != nil {
+		return mt.Example
+	}
+	names := make([]string, 0, len(mt.Examples))
+	for name := range mt.Examples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if v := mt.Examples[name].Value; v != nil {
+			return v
+		}
+	}
+	return d.example(mt.Schema, 0)
+}
+
+func isJSON(contentType string) bool {
+	return contentType == "application/json" || strings.HasSuffix(contentType, "+json")
+}
+
+// formatValue renders a parameter value the way a simple-style OpenAPI
+// serializer would.
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []any:
+		parts := make([]string, len(v))
+		for i, e := range v {
+			parts[i] = formatValue(e)
+		}
+		return strings.Join(parts, ",")
+	case map[string]any:
+		b, _ := json.Marshal(v)
+		return string(b)
+	default:
+		return fmt.Sprint(v)
+	}
+}
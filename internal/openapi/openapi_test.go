@@ -0,0 +1,192 @@
+package openapi
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const spec = `{
+  "openapi": "3.0.3",
+  "servers": [{"url": "https://{env}.api.test/v1", "variables": {"env": {"default": "staging"}}}],
+  "paths": {
+    "/pets": {
+      "get": {
+        "operationId": "listPets",
+        "tags": ["pets"],
+        "parameters": [
+          {"name": "limit", "in": "query", "required": true, "schema": {"type": "integer", "minimum": 10}},
+          {"name": "sort", "in": "query", "schema": {"type": "string"}},
+          {"name": "tag", "in": "query", "schema": {"type": "string", "example": "dog"}}
+        ]
+      },
+      "post": {
+        "operationId": "createPet",
+        "tags": ["pets"],
+        "parameters": [{"$ref": "#/components/parameters/Tenant"}],
+        "requestBody": {
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Pet"}}}
+        }
+      }
+    },
+    "/pets/{petId}": {
+      "parameters": [{"name": "petId", "in": "path", "required": true, "schema": {"type": "string", "format": "uuid"}}],
+      "delete": {"tags": ["admin"]}
+    },
+    "/login": {
+      "post": {
+        "operationId": "login",
+        "requestBody": {
+          "content": {"application/x-www-form-urlencoded": {"example": {"user": "demo"}}}
+        }
+      }
+    }
+  },
+  "components": {
+    "parameters": {
+      "Tenant": {"name": "X-Tenant", "in": "header", "required": true, "schema": {"type": "string", "enum": ["acme", "globex"]}}
+    },
+    "schemas": {
+      "Pet": {
+        "type": "object",
+        "properties": {
+          "name": {"type": "string", "example": "Rex"},
+          "born": {"type": "string", "format": "date"},
+          "tags": {"type": "array", "items": {"type": "string"}},
+          "owner": {"$ref": "#/components/schemas/Owner"}
+        }
+      },
+      "Owner": {
+        "allOf": [
+          {"type": "object", "properties": {"id": {"type": "integer"}}},
+          {"type": "object", "properties": {"pets": {"type": "array", "items": {"$ref": "#/components/schemas/Pet"}}}}
+        ]
+      }
+    }
+  }
+}`
+
+func TestScenario(t *testing.T) {
+	doc, err := Parse(strings.NewReader(spec))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s, err := doc.Scenario(Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Sequential() {
+		t.Error("expected a weighted scenario")
+	}
+
+	var names []string
+	for _, target := range s.Targets {
+		names = append(names, target.Name)
+	}
+	want := "login listPets createPet DELETE /pets/{petId}"
+	if got := strings.Join(names, " "); got != want {
+		t.Fatalf("targets = %q, want %q", got, want)
+	}
+
+	login := s.Targets[0]
+	if login.Body != "user=demo" || login.Headers["Content-Type"] != "application/x-www-form-urlencoded" {
+		t.Errorf("login body = %q, headers = %v", login.Body, login.Headers)
+	}
+
+	list := s.Targets[1]
+	if list.Method != "GET" || list.URL != "https://staging.api.test/v1/pets?limit=10&tag=dog" {
+		t.Errorf("listPets = %s %s", list.Method, list.URL)
+	}
+
+	create := s.Targets[2]
+	if create.Headers["X-Tenant"] != "acme" {
+		t.Errorf("createPet headers = %v, want X-Tenant: acme", create.Headers)
+	}
+	var pet map[string]any
+	if err := json.Unmarshal([]byte(create.Body), &pet); err != nil {
+		t.Fatalf("createPet body %q is not JSON: %v", create.Body, err)
+	}
+	if pet["name"] != "Rex" || pet["born"] != "2024-01-01" {
+		t.Errorf("createPet body = %s", create.Body)
+	}
+	if _, ok := pet["owner"].(map[string]any)["id"]; !ok {
+		t.Errorf("createPet body is missing owner.id: %s", create.Body)
+	}
+
+	del := s.Targets[3]
+	if del.URL != "https://staging.api.test/v1/pets/00000000-0000-4000-8000-000000000000" {
+		t.Errorf("DELETE URL = %s", del.URL)
+	}
+
+	// Every generated target must be accepted by the runner.
+	if _, err := s.ConfigTargets(); err != nil {
+		t.Errorf("ConfigTargets() error: %v", err)
+	}
+}
+
+func TestScenario_Selection(t *testing.T) {
+	doc, err := Parse(strings.NewReader(spec))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{"operation id", Options{Operations: []string{"login"}}, []string{"login"}},
+		{"method and path", Options{Operations: []string{"delete /pets/{petId}"}}, []string{"DELETE /pets/{petId}"}},
+		{"tag", Options{Tags: []string{"pets"}}, []string{"listPets", "createPet"}},
+		{"exclude", Options{Tags: []string{"pets"}, Exclude: []*regexp.Regexp{regexp.MustCompile(`^POST`)}}, []string{"listPets"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := doc.Scenario(tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for _, target := range s.Targets {
+				got = append(got, target.Name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("targets = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := doc.Scenario(Options{Tags: []string{"missing"}}); err == nil {
+		t.Error("expected error when no operation is selected")
+	}
+}
+
+func TestScenario_Server(t *testing.T) {
+	doc, err := Parse(strings.NewReader(`{"openapi": "3.1.0", "servers": [{"url": "/api"}],
+		"paths": {"/health": {"get": {}}}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := doc.Scenario(Options{}); err == nil {
+		t.Error("expected error for a relative server URL")
+	}
+
+	s, err := doc.Scenario(Options{Server: "http://localhost:8080/api/"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Targets[0].URL != "http://localhost:8080/api/health" {
+		t.Errorf("URL = %s", s.Targets[0].URL)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, input := range []string{`{"swagger": "2.0"}`, `openapi: 3.0.0`} {
+		if _, err := Parse(strings.NewReader(input)); err == nil {
+			t.Errorf("Parse(%q) expected error", input)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package openapi
+
+import "strings"
+
+// maxDepth stops example generation for recursive schemas.
+const maxDepth = 8
+
+// Schema is the subset of a JSON schema used to generate examples.
+type Schema struct {
+	Ref        string             `json:"$ref"`
+	Type       any                `json:"type"` // a string, or a list in OpenAPI 3.1
+	Format     string             `json:"format"`
+	Properties map[string]*Schema `json:"properties"`
+	Items      *Schema            `json:"items"`
+	Enum       []any              `json:"enum"`
+	Example    any                `json:"example"`
+	Examples   []any              `json:"examples"`
+	Default    any                `json:"default"`
+	Minimum    *float64           `json:"minimum"`
+	AllOf      []*Schema          `json:"allOf"`
+	OneOf      []*Schema          `json:"oneOf"`
+	AnyOf      []*Schema          `json:"anyOf"`
+}
+
+// example generates a value that satisfies the schema, preferring the
+// examples and defaults written in the spec.
+func (d *Document) example(s *Schema, depth int) any {
+	if s == nil || depth > maxDepth {
+		return nil
+	}
+	if s.Ref != "" {
+		name, ok := strings.CutPrefix(s.Ref, "#/components/schemas/")
+		if !ok {
+			return nil
+		}
+		return d.example(d.Components.Schemas[name], depth+1)
+	}
+
+	switch {
+	case s.Example != nil:
+		return s.Example
+	case len(s.Examples) > 0:
+		return s.Examples[0]
+	case s.Default != nil:
+		return s.Default
+	case len(s.Enum) > 0:
+		return s.Enum[0]
+	case len(s.AllOf) > 0:
+		merged := map[string]any{}
+		for _, sub := range s.AllOf {
+			if obj, ok := d.example(sub, depth+1).(map[string]any); ok {
+				for k, v := range obj {
+					merged[k] = v
+				}
+			}
+		}
+		return merged
+	case len(s.OneOf) > 0:
+		return d.example(s.OneOf[0], depth+1)
+	case len(s.AnyOf) > 0:
+		return d.example(s.AnyOf[0], depth+1)
+	}
+
+	switch s.typeName() {
+	case "object":
+		obj := map[string]any{}
+		for name, prop := range s.Properties {
+			if v := d.example(prop, depth+1); v != nil {
+				obj[name] = v
+			}
+		}
+		return obj
+	case "array":
+		if item := d.example(s.Items, depth+1); item != nil {
+			return []any{item}
+		}
+		return []any{}
+	case "integer":
+		if s.Minimum != nil {
+			return int64(*s.Minimum)
+		}
+		return 1
+	case "number":
+		if s.Minimum != nil {
+			return *s.Minimum
+		}
+		return 1.5
+	case "boolean":
+		return true
+	case "string":
+		return stringExample(s.Format)
+	}
+	if len(s.Properties) > 0 {
+		return d.example(&Schema{Type: "object", Properties: s.Properties}, depth)
+	}
+	return nil
+}
+
+// typeName returns the schema type, taking the first non-null entry of an
+// OpenAPI 3.1 type list.
+func (s *Schema) typeName() string {
+	switch t := s.Type.(type) {
+	case string:
+		return t
+	case []any:
+		for _, e := range t {
+			if name, ok := e.(string); ok && name != "null" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+func stringExample(format string) string {
+	switch format {
+	case "date":
+		return "2024-01-01"
+	case "date-time":
+		return "2024-01-01T00:00:00Z"
+	case "email":
+		return "user@example.com"
+	case "uuid":
+		return "00000000-0000-4000-8000-000000000000"
+	case "uri", "url":
+		return "https://example.com"
+	case "ipv4":
+		return "192.0.2.1"
+	case "ipv6":
+		return "2001:db8::1"
+	case "byte":
+		return "c3RyaW5n"
+	}
+	return "string"
+}
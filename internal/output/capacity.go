@@ -0,0 +1,58 @@
+package output
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/EsteveSegura/BrickHauler/internal/capacity"
+	"github.com/EsteveSegura/BrickHauler/internal/version"
+)
+
+// PrintCapacityHeader introduces a capacity search. unit names the load
+// level, such as "users" or "rps".
+func (w *Writer) PrintCapacityHeader(target, unit string, slo capacity.SLO, stepDuration time.Duration) {
+	fmt.Fprintf(w.w, "\nBrickHauler %s - capacity search\n", version.Version)
+	fmt.Fprintf(w.w, "================================================\n\n")
+	fmt.Fprintf(w.w, "Target:                  %s\n", target)
+	fmt.Fprintf(w.w, "Step Duration:           %v\n", stepDuration)
+	fmt.Fprintf(w.w, "Objective:               %s\n\n", describeSLO(slo))
+	fmt.Fprintf(w.w, "  %8s  %10s  %10s  %10s  %8s  %s\n", unit, "req/s", "p95", "p99", "errors", "result")
+}
+
+// PrintCapacityStep prints one step of a capacity search as it completes.
+func (w *Writer) PrintCapacityStep(step capacity.Step) {
+	result := "pass"
+	if !step.Pass {
+		result = "FAIL: " + step.Reason
+	}
+	fmt.Fprintf(w.w, "  %8d  %10.1f  %10v  %10v  %7.2f%%  %s\n",
+		step.Level, step.RPS, step.P95.Round(time.Millisecond), step.P99.Round(time.Millisecond), step.ErrorRate*100, result)
+}
+
+// PrintCapacity prints the outcome of a capacity search.
+func (w *Writer) PrintCapacity(res *capacity.Result, unit string) {
+	fmt.Fprintf(w.w, "\nCapacity:\n")
+	fmt.Fprintf(w.w, "---------\n")
+	if res.Best == nil {
+		fmt.Fprintf(w.w, "No level met the objective\n\n")
+		return
+	}
+	fmt.Fprintf(w.w, "Max Sustainable:         %d %s (%.1f req/s, p95 %v)\n",
+		res.Best.Level, unit, res.Best.RPS, res.Best.P95.Round(time.Millisecond))
+	if res.Knee > 0 {
+		fmt.Fprintf(w.w, "Knee Point:              between %d and %d %s\n\n", res.Best.Level, res.Knee, unit)
+	} else {
+		fmt.Fprintf(w.w, "Knee Point:              not reached; raise --max to keep searching\n\n")
+	}
+}
+
+func describeSLO(slo capacity.SLO) string {
+	s := fmt.Sprintf("errors <= %.2f%%", slo.MaxErrorRate*100)
+	if slo.P95 > 0 {
+		s += fmt.Sprintf(", p95 <= %v", slo.P95)
+	}
+	if slo.P99 > 0 {
+		s += fmt.Sprintf(", p99 <= %v", slo.P99)
+	}
+	return s
+}
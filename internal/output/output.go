@@ -3,6 +3,9 @@ package output
 import (
 	"fmt"
 	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/EsteveSegura/BrickHauler/internal/config"
@@ -25,10 +28,62 @@ func (w *Writer) PrintResults(cfg *config.Config, snap metrics.Snapshot, duratio
 	fmt.Fprintf(w.w, "\nBrickHauler %s\n", version.Version)
 	fmt.Fprintf(w.w, "================================================\n\n")
 
-	fmt.Fprintf(w.w, "Target URL:              %s\n", cfg.URI)
-	fmt.Fprintf(w.w, "HTTP Method:             %s\n", cfg.Method)
-	fmt.Fprintf(w.w, "Concurrency:             %d\n", cfg.Concurrency)
-	fmt.Fprintf(w.w, "Total Requests:          %d\n\n", cfg.Requests)
+	if cfg.Replay != nil {
+		fmt.Fprintf(w.w, "Replay:                  %s at %gx speed\n", cfg.URI, cfg.Replay.Speed)
+	} else if len(cfg.Targets) > 0 && cfg.Sequence {
+		fmt.Fprintf(w.w, "Targets:                 %d (in sequence)\n", len(cfg.Targets))
+	} else if len(cfg.Targets) > 0 {
+		fmt.Fprintf(w.w, "Targets:                 %d\n", len(cfg.Targets))
+	} else {
+		fmt.Fprintf(w.w, "Target URL:              %s\n", cfg.URI)
+	}
+	if cfg.UnixSocket != "" {
+		fmt.Fprintf(w.w, "Unix Socket:             %s\n", cfg.UnixSocket)
+	}
+	switch len(cfg.Proxies) {
+	case 0:
+	case 1:
+		fmt.Fprintf(w.w, "Proxy:                   %s\n", cfg.Proxies[0].Redacted())
+	default:
+		fmt.Fprintf(w.w, "Proxies:                 %d (%s)\n", len(cfg.Proxies), cfg.ProxyRotation)
+	}
+	if len(cfg.Targets) == 0 && cfg.Replay == nil {
+		fmt.Fprintf(w.w, "HTTP Method:             %s\n", cfg.Method)
+	}
+	fmt.Fprintf(w.w, "HTTP Version:            %s\n", cfg.HTTPVersion)
+	if cfg.Profile != nil {
+		fmt.Fprintf(w.w, "Load Profile:            %s\n", cfg.Profile)
+		fmt.Fprintf(w.w, "Max Concurrency:         %d\n", cfg.Concurrency)
+	} else if cfg.Adjustable {
+		fmt.Fprintf(w.w, "Concurrency:             %d (adjustable)\n", cfg.Concurrency)
+	} else {
+		fmt.Fprintf(w.w, "Concurrency:             %d\n", cfg.Concurrency)
+	}
+	if len(cfg.SourceIPs) > 0 {
+		fmt.Fprintf(w.w, "Source IPs:              %d\n", len(cfg.SourceIPs))
+	}
+	if !cfg.ThinkTime.IsZero() {
+		fmt.Fprintf(w.w, "Think Time:              %s\n", cfg.ThinkTime)
+	}
+	if cfg.MaxRPS > 0 {
+		fmt.Fprintf(w.w, "Max RPS:                 %g\n", cfg.MaxRPS)
+	}
+	if cfg.Pacing > 0 {
+		fmt.Fprintf(w.w, "Pacing:                  one iteration every %v\n", cfg.Pacing)
+	}
+	if cfg.IsolateConnections {
+		fmt.Fprintf(w.w, "Connections:             isolated per virtual user\n")
+	}
+	if cfg.Duration > 0 {
+		fmt.Fprintf(w.w, "Duration:                %v\n", cfg.Duration)
+	}
+	if !cfg.Warmup.IsZero() {
+		fmt.Fprintf(w.w, "Warm-up:                 %s (not measured)\n", cfg.Warmup)
+	}
+	if cfg.Requests > 0 {
+		fmt.Fprintf(w.w, "Total Requests:          %d\n", cfg.Requests)
+	}
+	fmt.Fprintln(w.w)
 
 	fmt.Fprintf(w.w, "Results:\n")
 	fmt.Fprintf(w.w, "--------\n")
@@ -48,6 +103,85 @@ func (w *Writer) PrintResults(cfg *config.Config, snap metrics.Snapshot, duratio
 	fmt.Fprintf(w.w, "Total Duration:          %v\n\n", duration.Round(time.Millisecond))
 
 	w.printPercentiles(snap)
+	w.printNegotiated(snap)
+}
+
+// ReplayStats describes how closely a replay kept to the recorded schedule.
+type ReplayStats struct {
+	// Late counts requests sent behind schedule because every worker was
+	// busy.
+	Late int
+	// MaxLag is the longest such delay.
+	MaxLag time.Duration
+}
+
+// PrintReplay reports how closely a replay kept to the recorded schedule.
+func (w *Writer) PrintReplay(stats ReplayStats) {
+	fmt.Fprintf(w.w, "Replay Schedule:\n")
+	fmt.Fprintf(w.w, "----------------\n")
+	if stats.Late == 0 {
+		fmt.Fprintf(w.w, "All requests sent on time\n\n")
+		return
+	}
+	fmt.Fprintf(w.w, "Late Requests:           %d (raise --concurrent to keep up)\n", stats.Late)
+	fmt.Fprintf(w.w, "Max Lag:                 %v\n\n", stats.MaxLag.Round(time.Millisecond))
+}
+
+// RateLimitStats describes how often the --max-rps limiter held requests
+// back.
+type RateLimitStats struct {
+	MaxRPS float64
+	// Requests counts the requests that went through the limiter, and
+	// Throttled those that had to wait for it.
+	Requests  int64
+	Throttled int64
+	// Waited is the total time spent waiting.
+	Waited time.Duration
+}
+
+// PrintRateLimit reports whether the rate limiter or the target was the
+// bottleneck. When most requests waited for the limiter, the virtual users
+// could have gone faster; otherwise they were busy waiting on responses.
+func (w *Writer) PrintRateLimit(stats RateLimitStats) {
+	fmt.Fprintf(w.w, "Rate Limit:\n")
+	fmt.Fprintf(w.w, "-----------\n")
+	fmt.Fprintf(w.w, "Max RPS:                 %g\n", stats.MaxRPS)
+	if stats.Requests == 0 {
+		fmt.Fprintln(w.w)
+		return
+	}
+
+	share := float64(stats.Throttled) / float64(stats.Requests)
+	fmt.Fprintf(w.w, "Throttled Requests:      %d (%.1f%%)\n", stats.Throttled, share*100)
+	if stats.Throttled > 0 {
+		avg := stats.Waited / time.Duration(stats.Throttled)
+		fmt.Fprintf(w.w, "Avg Throttle Wait:       %v\n", avg.Round(time.Microsecond))
+	}
+	if share >= 0.5 {
+		fmt.Fprintf(w.w, "Bottleneck:              rate limiter\n\n")
+	} else {
+		fmt.Fprintf(w.w, "Bottleneck:              target (raise --concurrent to reach the limit)\n\n")
+	}
+}
+
+// TimelineEvent is a change made while the test was running.
+type TimelineEvent struct {
+	// At is the time since the measured run started.
+	At     time.Duration
+	Event  string
+	Source string
+}
+
+// PrintTimeline lists the changes made while the test was running.
+func (w *Writer) PrintTimeline(events []TimelineEvent) {
+	fmt.Fprintf(w.w, "Timeline:\n")
+	fmt.Fprintf(w.w, "---------\n")
+	tw := tabwriter.NewWriter(w.w, 0, 0, 2, ' ', 0)
+	for _, e := range events {
+		fmt.Fprintf(tw, "  %v\t%s\t(%s)\n", e.At.Round(100*time.Millisecond), e.Event, e.Source)
+	}
+	tw.Flush()
+	fmt.Fprintln(w.w)
 }
 
 func (w *Writer) printPercentiles(snap metrics.Snapshot) {
@@ -65,12 +199,74 @@ func (w *Writer) printPercentiles(snap metrics.Snapshot) {
 	fmt.Fprintln(w.w)
 }
 
+func (w *Writer) printNegotiated(snap metrics.Snapshot) {
+	if len(snap.Protocols) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w.w, "Negotiated:\n")
+	fmt.Fprintf(w.w, "-----------\n")
+	w.printCounts("Protocol", snap.Protocols)
+	w.printCounts("TLS", snap.TLSVersions)
+	w.printCounts("Cipher", snap.CipherSuites)
+	fmt.Fprintln(w.w)
+}
+
+// printCounts prints one line per key, sorted by key.
+func (w *Writer) printCounts(label string, counts map[string]int64) {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fmt.Fprintf(w.w, "  %-8s %s: %d\n", label, k, counts[k])
+	}
+}
+
+// PrintBreakdown outputs a table of results per key, such as per proxy.
+func (w *Writer) PrintBreakdown(label string, snaps map[string]metrics.Snapshot) {
+	if len(snaps) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(snaps))
+	for k := range snaps {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	title := "Per " + label + ":"
+	fmt.Fprintf(w.w, "%s\n", title)
+	fmt.Fprintf(w.w, "%s\n", strings.Repeat("-", len(title)))
+
+	tw := tabwriter.NewWriter(w.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "  %s\tSuccessful\tFailed\tAvg\tP95\n", label)
+	for _, k := range keys {
+		snap := snaps[k]
+		fmt.Fprintf(tw, "  %s\t%d\t%d\t%v\t%v\n",
+			k, snap.SuccessCount, snap.FailureCount, snap.AverageTime(), snap.Percentile(95))
+	}
+	tw.Flush()
+	fmt.Fprintln(w.w)
+}
+
 // PrintProgress outputs real-time progress during the test.
 func (w *Writer) PrintProgress(completed, total int64, duration time.Duration) {
 	rps := float64(completed) / duration.Seconds()
+	if total == 0 {
+		fmt.Fprintf(w.w, "\rProgress: %d requests in %v (%.1f req/s)", completed, duration.Round(time.Second), rps)
+		return
+	}
 	fmt.Fprintf(w.w, "\rProgress: %d/%d requests (%.1f req/s)", completed, total, rps)
 }
 
+// PrintWarmup announces the warm-up before the measured run starts.
+func (w *Writer) PrintWarmup(warmup config.Warmup) {
+	fmt.Fprintf(w.w, "Warming up with %s...\n", warmup)
+}
+
 // PrintShutdown outputs a shutdown message.
 func (w *Writer) PrintShutdown() {
 	fmt.Fprintln(w.w, "\nShutting down gracefully...")
@@ -0,0 +1,183 @@
+// Package prometheus exposes the live metrics of a run in the Prometheus
+// text exposition format, so dashboards can plot the load generator's view
+// next to the target's own metrics.
+package prometheus
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/EsteveSegura/BrickHauler/internal/metrics"
+)
+
+// Buckets are the upper bounds of the latency histogram, in seconds.
+var Buckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// requestKey labels the request counter.
+type requestKey struct {
+	target, method, status, errorClass string
+}
+
+// latencyKey labels the latency histogram.
+type latencyKey struct {
+	target, method, status string
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// gauge is a value read when scraped.
+type gauge struct {
+	name, help string
+	value      func() float64
+}
+
+// Exporter collects samples from a run and serves them to Prometheus. It
+// implements metrics.Observer and http.Handler.
+type Exporter struct {
+	mu        sync.Mutex
+	requests  map[requestKey]uint64
+	latencies map[latencyKey]*histogram
+	gauges    []gauge
+}
+
+// New returns an empty Exporter.
+func New() *Exporter {
+	return &Exporter{
+		requests:  make(map[requestKey]uint64),
+		latencies: make(map[latencyKey]*histogram),
+	}
+}
+
+// Gauge adds a gauge whose value is read from value on every scrape. It
+// must be called before the exporter is served.
+func (e *Exporter) Gauge(name, help string, value func() float64) {
+	e.gauges = append(e.gauges, gauge{name: name, help: help, value: value})
+}
+
+// Observe counts a completed request and, when a response came back,
+// records its latency.
+func (e *Exporter) Observe(s metrics.Sample) {
+	status := "none"
+	if s.Status > 0 {
+		status = strconv.Itoa(s.Status)
+	}
+	errorClass := s.Error
+	if errorClass == metrics.ErrorNone {
+		errorClass = "none"
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.requests[requestKey{s.Target, s.Method, status, errorClass}]++
+	if s.Status == 0 {
+		return
+	}
+
+	key := latencyKey{s.Target, s.Method, status}
+	h, ok := e.latencies[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(Buckets))}
+		e.latencies[key] = h
+	}
+	seconds := s.Duration.Seconds()
+	if i := sort.SearchFloat64s(Buckets, seconds); i < len(Buckets) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// ServeHTTP writes every metric in the text exposition format.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	e.Write(w)
+}
+
+// Write writes every metric in the text exposition format, sorted by
+// labels so consecutive scrapes line up.
+func (e *Exporter) Write(w io.Writer) {
+	// Copy under the lock so slow scrapers do not hold up the run.
+	e.mu.Lock()
+	requests := make(map[requestKey]uint64, len(e.requests))
+	for k, n := range e.requests {
+		requests[k] = n
+	}
+	latencies := make(map[latencyKey]histogram, len(e.latencies))
+	for k, h := range e.latencies {
+		latencies[k] = histogram{counts: append([]uint64(nil), h.counts...), count: h.count, sum: h.sum}
+	}
+	e.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP brickhauler_requests_total Requests completed, by target, method, status and error class.")
+	fmt.Fprintln(w, "# TYPE brickhauler_requests_total counter")
+	for _, k := range sortedKeys(requests, func(k requestKey) string {
+		return k.target + "\x00" + k.method + "\x00" + k.status + "\x00" + k.errorClass
+	}) {
+		fmt.Fprintf(w, "brickhauler_requests_total{%s} %d\n",
+			labels("target", k.target, "method", k.method, "status", k.status, "error_class", k.errorClass), requests[k])
+	}
+
+	fmt.Fprintln(w, "# HELP brickhauler_request_duration_seconds Response times of requests that got a response.")
+	fmt.Fprintln(w, "# TYPE brickhauler_request_duration_seconds histogram")
+	for _, k := range sortedKeys(latencies, func(k latencyKey) string {
+		return k.target + "\x00" + k.method + "\x00" + k.status
+	}) {
+		h := latencies[k]
+		base := labels("target", k.target, "method", k.method, "status", k.status)
+		var cumulative uint64
+		for i, le := range Buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(w, "brickhauler_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n", base, formatFloat(le), cumulative)
+		}
+		fmt.Fprintf(w, "brickhauler_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", base, h.count)
+		fmt.Fprintf(w, "brickhauler_request_duration_seconds_sum{%s} %s\n", base, formatFloat(h.sum))
+		fmt.Fprintf(w, "brickhauler_request_duration_seconds_count{%s} %d\n", base, h.count)
+	}
+
+	for _, g := range e.gauges {
+		fmt.Fprintf(w, "# HELP %s %s\n", g.name, g.help)
+		fmt.Fprintf(w, "# TYPE %s gauge\n", g.name)
+		fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(g.value()))
+	}
+}
+
+// sortedKeys returns the keys of m ordered by the string sortKey builds.
+func sortedKeys[K comparable, V any](m map[K]V, sortKey func(K) string) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return sortKey(keys[i]) < sortKey(keys[j]) })
+	return keys
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// labels formats name/value pairs as a label set, escaping the values.
+func labels(pairs ...string) string {
+	var b strings.Builder
+	for i := 0; i < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(pairs[i])
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(pairs[i+1]))
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
@@ -0,0 +1,52 @@
+package prometheus
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EsteveSegura/BrickHauler/internal/metrics"
+)
+
+func TestExporter(t *testing.T) {
+	e := New()
+	e.Gauge("brickhauler_active_users", "Virtual users running.", func() float64 { return 7 })
+
+	e.Observe(metrics.Sample{Target: "home", Method: "GET", Status: 200, Duration: 250 * time.Millisecond})
+	e.Observe(metrics.Sample{Target: "home", Method: "GET", Status: 200, Duration: 500 * time.Millisecond})
+	e.Observe(metrics.Sample{Target: "home", Method: "GET", Status: 503, Error: metrics.ErrorHTTP, Duration: 20 * time.Second})
+	e.Observe(metrics.Sample{Target: `say "hi"`, Method: "POST", Error: metrics.ErrorTimeout, Duration: time.Second})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("content type = %q", ct)
+	}
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		"# TYPE brickhauler_requests_total counter\n",
+		`brickhauler_requests_total{target="home",method="GET",status="200",error_class="none"} 2` + "\n",
+		`brickhauler_requests_total{target="home",method="GET",status="503",error_class="http_status"} 1` + "\n",
+		`brickhauler_requests_total{target="say \"hi\"",method="POST",status="none",error_class="timeout"} 1` + "\n",
+		"# TYPE brickhauler_request_duration_seconds histogram\n",
+		`brickhauler_request_duration_seconds_bucket{target="home",method="GET",status="200",le="0.1"} 0` + "\n",
+		`brickhauler_request_duration_seconds_bucket{target="home",method="GET",status="200",le="0.25"} 1` + "\n",
+		`brickhauler_request_duration_seconds_bucket{target="home",method="GET",status="200",le="0.5"} 2` + "\n",
+		`brickhauler_request_duration_seconds_bucket{target="home",method="GET",status="200",le="+Inf"} 2` + "\n",
+		`brickhauler_request_duration_seconds_sum{target="home",method="GET",status="200"} 0.75` + "\n",
+		`brickhauler_request_duration_seconds_bucket{target="home",method="GET",status="503",le="10"} 0` + "\n",
+		`brickhauler_request_duration_seconds_count{target="home",method="GET",status="503"} 1` + "\n",
+		"brickhauler_active_users 7\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %q in:\n%s", want, body)
+		}
+	}
+
+	// Requests without a response have no latency to record.
+	if strings.Contains(body, `brickhauler_request_duration_seconds_count{target="say`) {
+		t.Error("a timed out request was added to the latency histogram")
+	}
+}
@@ -0,0 +1,293 @@
+// Package record implements a forward proxy that captures the requests
+// passing through it and turns them into a BrickHauler scenario.
+package record
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/EsteveSegura/BrickHauler/internal/har"
+	"github.com/EsteveSegura/BrickHauler/internal/scenario"
+)
+
+// hopHeaders only apply to a single connection and are never forwarded.
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// exchange is one recorded request and how long its response took.
+type exchange struct {
+	start    time.Time
+	duration time.Duration
+	method   string
+	url      string
+	header   http.Header
+	body     []byte
+}
+
+// Recorder is an http.Handler acting as a forward proxy. Plain HTTP
+// requests are forwarded as they are; HTTPS tunnels opened with CONNECT
+// are intercepted with certificates issued by the CA.
+type Recorder struct {
+	ca        *CA
+	transport *http.Transport
+
+	mu        sync.Mutex
+	exchanges []exchange
+}
+
+// New returns a Recorder. upstreamTLS configures the connections to the
+// real servers and may be nil.
+func New(ca *CA, upstreamTLS *tls.Config) *Recorder {
+	return &Recorder{
+		ca: ca,
+		transport: &http.Transport{
+			TLSClientConfig: upstreamTLS,
+			// Pass compressed bodies through untouched.
+			DisableCompression: true,
+			IdleConnTimeout:    90 * time.Second,
+		},
+	}
+}
+
+// Len returns the number of requests recorded so far.
+func (r *Recorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.exchanges)
+}
+
+// Close releases the upstream connections.
+func (r *Recorder) Close() {
+	r.transport.CloseIdleConnections()
+}
+
+// ServeHTTP implements http.Handler.
+func (r *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodConnect {
+		r.intercept(w, req)
+		return
+	}
+	if !req.URL.IsAbs() {
+		http.Error(w, "this is a recording proxy: configure it as the HTTP proxy", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := r.forward(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	removeHopHeaders(resp.Header)
+	for k, v := range resp.Header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+// intercept terminates TLS for a CONNECT tunnel and forwards the requests
+// sent through it.
+func (r *Recorder) intercept(w http.ResponseWriter, req *http.Request) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "tunnelling not supported", http.StatusInternalServerError)
+		return
+	}
+	conn, _, err := hj.Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	if _, err := io.WriteString(conn, "HTTP/1.1 200 Connection Established\r\n\r\n"); err != nil {
+		return
+	}
+
+	authority := req.Host
+	host, port, err := net.SplitHostPort(authority)
+	if err != nil {
+		host, port = authority, "443"
+	}
+	if port == "443" {
+		authority = host
+	}
+
+	tlsConn := tls.Server(conn, &tls.Config{
+		NextProtos: []string{"http/1.1"},
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			name := hello.ServerName
+			if name == "" {
+				name = host
+			}
+			return r.ca.leaf(name)
+		},
+	})
+	if err := tlsConn.Handshake(); err != nil {
+		return
+	}
+
+	br := bufio.NewReader(tlsConn)
+	for {
+		in, err := http.ReadRequest(br)
+		if err != nil {
+			return
+		}
+		in.URL.Scheme = "https"
+		in.URL.Host = authority
+
+		resp, err := r.forward(in)
+		if err != nil {
+			resp = &http.Response{
+				StatusCode: http.StatusBadGateway,
+				ProtoMajor: 1,
+				ProtoMinor: 1,
+				Header:     http.Header{"Content-Type": {"text/plain; charset=utf-8"}},
+				Body:       io.NopCloser(strings.NewReader(err.Error())),
+				Close:      true,
+			}
+		}
+		removeHopHeaders(resp.Header)
+		err = resp.Write(tlsConn)
+		resp.Body.Close()
+		if err != nil || in.Close || resp.Close {
+			return
+		}
+	}
+}
+
+// forward sends req to the real server and records it. The response body
+// is read in full so the recorded duration covers the whole exchange.
+func (r *Recorder) forward(req *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading request body: %w", err)
+	}
+
+	out := req.Clone(req.Context())
+	out.RequestURI = ""
+	out.Header = req.Header.Clone()
+	removeHopHeaders(out.Header)
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	out.ContentLength = int64(len(body))
+	if len(body) == 0 {
+		out.Body = nil
+	}
+
+	start := time.Now()
+	resp, err := r.transport.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	resp.ContentLength = int64(len(respBody))
+	resp.TransferEncoding = nil
+
+	r.mu.Lock()
+	r.exchanges = append(r.exchanges, exchange{
+		start:    start,
+		duration: time.Since(start),
+		method:   req.Method,
+		url:      out.URL.String(),
+		header:   out.Header,
+		body:     body,
+	})
+	r.mu.Unlock()
+
+	return resp, nil
+}
+
+func removeHopHeaders(h http.Header) {
+	for _, c := range h.Values("Connection") {
+		for _, name := range strings.Split(c, ",") {
+			h.Del(strings.TrimSpace(name))
+		}
+	}
+	for _, name := range hopHeaders {
+		h.Del(name)
+	}
+}
+
+// Options control the conversion to a scenario.
+type Options struct {
+	// Exclude drops requests whose URL matches any of the patterns.
+	Exclude []*regexp.Regexp
+}
+
+// Scenario converts the recorded requests into a sequential scenario that
+// replays them in the order they started, keeping the pauses between them.
+func (r *Recorder) Scenario(opts Options) (*scenario.Scenario, error) {
+	r.mu.Lock()
+	var exchanges []exchange
+	for _, e := range r.exchanges {
+		if !har.Excluded(e.url, opts.Exclude) {
+			exchanges = append(exchanges, e)
+		}
+	}
+	r.mu.Unlock()
+
+	if len(exchanges) == 0 {
+		return nil, fmt.Errorf("no requests recorded")
+	}
+	sort.SliceStable(exchanges, func(i, j int) bool {
+		return exchanges[i].start.Before(exchanges[j].start)
+	})
+
+	s := &scenario.Scenario{Mode: scenario.ModeSequence}
+	for i, e := range exchanges {
+		t := scenario.Target{Method: e.method, URL: e.url, Body: string(e.body)}
+
+		for name, values := range e.header {
+			if har.SkipHeader(name) || len(values) == 0 {
+				continue
+			}
+			if t.Headers == nil {
+				t.Headers = make(map[string]string)
+			}
+			t.Headers[name] = strings.Join(values, ", ")
+		}
+
+		for _, c := range e.header.Values("Cookie") {
+			for _, pair := range strings.Split(c, ";") {
+				if pair = strings.TrimSpace(pair); pair != "" {
+					t.Cookies = append(t.Cookies, pair)
+				}
+			}
+		}
+
+		if i+1 < len(exchanges) {
+			gap := exchanges[i+1].start.Sub(e.start.Add(e.duration))
+			if gap > 0 {
+				t.ThinkTime = scenario.Duration(gap.Round(time.Millisecond))
+			}
+		}
+
+		s.Targets = append(s.Targets, t)
+	}
+	return s, nil
+}
@@ -0,0 +1,151 @@
+package record
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func echoServer(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	io.WriteString(w, r.Method+" "+r.URL.Path+" "+string(body))
+}
+
+// proxyClient returns a client that sends everything through rec.
+func proxyClient(t *testing.T, rec *Recorder, roots *x509.CertPool) *http.Client {
+	t.Helper()
+	proxy := httptest.NewServer(rec)
+	t.Cleanup(proxy.Close)
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	return &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyURL(proxyURL),
+		TLSClientConfig: &tls.Config{RootCAs: roots},
+	}}
+}
+
+func TestRecorder_HTTP(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(echoServer))
+	defer upstream.Close()
+
+	ca, err := NewCA()
+	if err != nil {
+		t.Fatalf("NewCA: %v", err)
+	}
+	rec := New(ca, nil)
+	defer rec.Close()
+	client := proxyClient(t, rec, nil)
+
+	req, _ := http.NewRequest("POST", upstream.URL+"/cart", strings.NewReader(`{"id":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Cookie", "sid=abc; theme=dark")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request through proxy: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != `POST /cart {"id":1}` {
+		t.Errorf("response = %q", body)
+	}
+
+	if _, err := client.Get(upstream.URL + "/logo.png"); err != nil {
+		t.Fatalf("request through proxy: %v", err)
+	}
+
+	s, err := rec.Scenario(Options{Exclude: []*regexp.Regexp{regexp.MustCompile(`\.png$`)}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !s.Sequential() || len(s.Targets) != 1 {
+		t.Fatalf("expected one sequential target, got %+v", s)
+	}
+
+	target := s.Targets[0]
+	if target.Method != "POST" || target.URL != upstream.URL+"/cart" || target.Body != `{"id":1}` {
+		t.Errorf("target = %s %s %q", target.Method, target.URL, target.Body)
+	}
+	if target.Headers["Content-Type"] != "application/json" {
+		t.Errorf("headers = %v", target.Headers)
+	}
+	for _, h := range []string{"Cookie", "Proxy-Connection", "Content-Length"} {
+		if _, ok := target.Headers[h]; ok {
+			t.Errorf("header %s should not be recorded", h)
+		}
+	}
+	if strings.Join(target.Cookies, ",") != "sid=abc,theme=dark" {
+		t.Errorf("cookies = %q", target.Cookies)
+	}
+}
+
+func TestRecorder_HTTPS(t *testing.T) {
+	upstream := httptest.NewTLSServer(http.HandlerFunc(echoServer))
+	defer upstream.Close()
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "ca.pem"), filepath.Join(dir, "ca-key.pem")
+	created, err := NewCA()
+	if err != nil {
+		t.Fatalf("NewCA: %v", err)
+	}
+	if err := created.Save(certFile, keyFile); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	ca, err := LoadCA(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("LoadCA: %v", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ca.CertPEM())
+
+	rec := New(ca, &tls.Config{InsecureSkipVerify: true})
+	defer rec.Close()
+	client := proxyClient(t, rec, roots)
+
+	for _, path := range []string{"/login", "/home"} {
+		resp, err := client.Get(upstream.URL + path)
+		if err != nil {
+			t.Fatalf("request through proxy: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != "GET "+path+" " {
+			t.Errorf("response = %q", body)
+		}
+	}
+
+	if rec.Len() != 2 {
+		t.Fatalf("recorded %d requests, want 2", rec.Len())
+	}
+	s, err := rec.Scenario(Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Targets[0].URL != upstream.URL+"/login" || s.Targets[1].URL != upstream.URL+"/home" {
+		t.Errorf("URLs = %s, %s", s.Targets[0].URL, s.Targets[1].URL)
+	}
+	if _, err := s.ConfigTargets(); err != nil {
+		t.Errorf("ConfigTargets() error: %v", err)
+	}
+}
+
+func TestRecorder_Empty(t *testing.T) {
+	rec := New(nil, nil)
+	if _, err := rec.Scenario(Options{}); err == nil {
+		t.Error("expected error with nothing recorded")
+	}
+
+	w := httptest.NewRecorder()
+	rec.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("direct request status = %d, want 400", w.Code)
+	}
+}
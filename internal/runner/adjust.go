@@ -0,0 +1,149 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/EsteveSegura/BrickHauler/internal/output"
+)
+
+// ErrNotAdjustable is returned when changing the load of a run that was
+// not started as adjustable.
+var ErrNotAdjustable = errors.New("the load of this run cannot be adjusted")
+
+// adjustments holds the live changes made to a run.
+type adjustments struct {
+	// users overrides the profile or Concurrency once set.
+	users    int
+	usersSet bool
+	started  time.Time
+	timeline []output.TimelineEvent
+
+	// resume is open while the run is paused and closed to resume it.
+	resume   chan struct{}
+	pausedAt time.Time
+	// paused is the total time spent paused before the current pause.
+	paused time.Duration
+	// end stops a run limited by Duration at deadline, pushed back by
+	// every pause.
+	end      *time.Timer
+	deadline time.Time
+}
+
+// wantUsers returns how many virtual users should be active at elapsed.
+func (r *Runner) wantUsers(elapsed time.Duration) int {
+	r.adjustMu.Lock()
+	defer r.adjustMu.Unlock()
+
+	switch {
+	case r.adjust.usersSet:
+		return r.adjust.users
+	case r.cfg.Profile != nil:
+		return r.cfg.Profile.Users(elapsed)
+	}
+	return r.cfg.Concurrency
+}
+
+// Users returns how many virtual users are wanted right now.
+func (r *Runner) Users() int {
+	return r.wantUsers(r.activeTime())
+}
+
+// SetUsers changes the number of active virtual users for the rest of the
+// run, replacing any load profile. source says where the change came from
+// for the results timeline.
+func (r *Runner) SetUsers(n int, source string) error {
+	return r.Adjust(&n, nil, source)
+}
+
+// MaxRPS returns the current rate limit, zero when there is none.
+func (r *Runner) MaxRPS() float64 {
+	return r.limiter.snapshot().MaxRPS
+}
+
+// SetMaxRPS changes the rate limit for the rest of the run; zero removes
+// it. source says where the change came from for the results timeline.
+func (r *Runner) SetMaxRPS(rps float64, source string) error {
+	return r.Adjust(nil, &rps, source)
+}
+
+// Adjust changes the virtual users, the rate limit or both, like SetUsers
+// and SetMaxRPS, leaving nil values as they are. Both values are checked
+// before either is applied, so an error leaves the load unchanged.
+func (r *Runner) Adjust(users *int, maxRPS *float64, source string) error {
+	if !r.cfg.Adjustable {
+		return ErrNotAdjustable
+	}
+	if users != nil {
+		if *users < 0 {
+			return fmt.Errorf("users cannot be negative, got %d", *users)
+		}
+		if r.cfg.IsolateConnections && *users > r.cfg.Concurrency {
+			return fmt.Errorf("isolated connections allow at most %d users, the --concurrent value", r.cfg.Concurrency)
+		}
+	}
+	if maxRPS != nil && *maxRPS < 0 {
+		return fmt.Errorf("max RPS cannot be negative, got %g", *maxRPS)
+	}
+
+	if users != nil {
+		r.setUsers(*users, source)
+	}
+	if maxRPS != nil {
+		before := r.MaxRPS()
+		r.limiter.setRate(*maxRPS)
+		r.logEvent(fmt.Sprintf("max rps %s -> %s", formatRate(before), formatRate(*maxRPS)), source)
+	}
+	return nil
+}
+
+// setUsers applies a checked number of virtual users.
+func (r *Runner) setUsers(n int, source string) {
+	before := r.Users()
+	r.adjustMu.Lock()
+	r.adjust.users, r.adjust.usersSet = n, true
+	r.adjustMu.Unlock()
+
+	r.logEvent(fmt.Sprintf("users %d -> %d", before, n), source)
+
+	// Wake the pool so the change applies at once.
+	select {
+	case r.adjusted <- struct{}{}:
+	default:
+	}
+}
+
+func formatRate(rps float64) string {
+	if rps == 0 {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%g", rps)
+}
+
+// logEvent adds an entry to the results timeline.
+func (r *Runner) logEvent(event, source string) {
+	at := r.sinceStart()
+
+	r.adjustMu.Lock()
+	defer r.adjustMu.Unlock()
+	r.adjust.timeline = append(r.adjust.timeline, output.TimelineEvent{At: at, Event: event, Source: source})
+}
+
+// sinceStart returns the wall-clock time since the measured run started,
+// pauses included, or zero before it starts.
+func (r *Runner) sinceStart() time.Duration {
+	r.adjustMu.Lock()
+	defer r.adjustMu.Unlock()
+	if r.adjust.started.IsZero() {
+		return 0
+	}
+	return time.Since(r.adjust.started)
+}
+
+// Timeline returns the changes made during the run, in order.
+func (r *Runner) Timeline() []output.TimelineEvent {
+	r.adjustMu.Lock()
+	defer r.adjustMu.Unlock()
+	return append([]output.TimelineEvent(nil), r.adjust.timeline...)
+}
@@ -0,0 +1,114 @@
+package runner
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/EsteveSegura/BrickHauler/internal/output"
+)
+
+// limiter is a token bucket shared by all workers. The bucket holds a
+// single token, so requests are spread evenly over each second instead of
+// leaving in bursts. Workers that find it empty reserve a later token and
+// wait for it. A zero rate lets every request through.
+type limiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	tokens float64
+	last   time.Time
+	stats  output.RateLimitStats
+}
+
+// newLimiter returns a limiter for rps requests per second, or nil when
+// rps is zero.
+func newLimiter(rps float64) *limiter {
+	if rps <= 0 {
+		return nil
+	}
+	return &limiter{
+		rate:   rps,
+		tokens: 1,
+		last:   time.Now(),
+		stats:  output.RateLimitStats{MaxRPS: rps},
+	}
+}
+
+// wait blocks until the caller may send a request, returning false if ctx
+// is cancelled first. A nil limiter never blocks.
+func (l *limiter) wait(ctx context.Context) bool {
+	if l == nil {
+		return true
+	}
+
+	l.mu.Lock()
+	if l.rate == 0 {
+		l.mu.Unlock()
+		return true
+	}
+	l.refill(time.Now())
+	l.tokens--
+
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+		l.stats.Throttled++
+	}
+	l.stats.Requests++
+	l.mu.Unlock()
+
+	if delay == 0 {
+		return true
+	}
+	start := time.Now()
+	ok := sleep(ctx, delay)
+
+	// Only count the time actually waited, as a cancelled wait is cut short.
+	l.mu.Lock()
+	l.stats.Waited += min(time.Since(start), delay)
+	l.mu.Unlock()
+	return ok
+}
+
+// refill adds the tokens earned since the last call. Callers hold mu.
+func (l *limiter) refill(now time.Time) {
+	l.tokens = min(1, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+}
+
+// setRate changes the rate to rps requests per second; zero removes the
+// limit. Requests already waiting keep their reservation.
+func (l *limiter) setRate(rps float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if l.rate == 0 {
+		l.tokens = 1
+		l.last = now
+	} else {
+		l.refill(now)
+	}
+	l.rate = rps
+	l.stats.MaxRPS = rps
+}
+
+// snapshot returns the limiter statistics so far. A nil limiter has none.
+func (l *limiter) snapshot() output.RateLimitStats {
+	if l == nil {
+		return output.RateLimitStats{}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stats
+}
+
+// resetStats clears the statistics gathered so far, keeping the bucket.
+func (l *limiter) resetStats() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stats = output.RateLimitStats{MaxRPS: l.rate}
+}
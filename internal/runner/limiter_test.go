@@ -0,0 +1,88 @@
+package runner
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLimiter_SpreadsRequests(t *testing.T) {
+	l := newLimiter(200)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				l.wait(context.Background())
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The first token is available at once; the other 19 come every 5ms.
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond || elapsed > 300*time.Millisecond {
+		t.Errorf("20 requests at 200 rps took %v, want about 95ms", elapsed)
+	}
+
+	stats := l.snapshot()
+	if stats.Requests != 20 || stats.Throttled < 18 {
+		t.Errorf("stats = %+v, want 20 requests, nearly all throttled", stats)
+	}
+}
+
+func TestLimiter_NilAndCancelled(t *testing.T) {
+	var none *limiter
+	if !none.wait(context.Background()) {
+		t.Error("nil limiter should never block")
+	}
+	if newLimiter(0) != nil {
+		t.Error("newLimiter(0) should disable limiting")
+	}
+
+	l := newLimiter(1)
+	l.wait(context.Background()) // takes the only token
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if l.wait(ctx) {
+		t.Error("wait should give up when the context is cancelled")
+	}
+	// The reserved second was cut short, so only the time waited counts.
+	if waited := l.snapshot().Waited; waited < 20*time.Millisecond || waited > 500*time.Millisecond {
+		t.Errorf("waited %v, want about the 20ms before the cancellation", waited)
+	}
+}
+
+func TestLimiter_SetRate(t *testing.T) {
+	l := &limiter{}
+	for i := 0; i < 100; i++ {
+		if !l.wait(context.Background()) {
+			t.Fatal("a zero rate should never block")
+		}
+	}
+
+	l.setRate(100)
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		l.wait(context.Background())
+	}
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("5 requests at 100 rps took %v, want about 40ms", elapsed)
+	}
+	if stats := l.snapshot(); stats.MaxRPS != 100 || stats.Requests != 5 {
+		t.Errorf("stats = %+v, want 5 requests at 100 rps", stats)
+	}
+
+	l.setRate(0)
+	start = time.Now()
+	for i := 0; i < 100; i++ {
+		l.wait(context.Background())
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Millisecond {
+		t.Errorf("removing the limit still took %v", elapsed)
+	}
+}
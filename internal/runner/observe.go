@@ -0,0 +1,106 @@
+package runner
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/EsteveSegura/BrickHauler/internal/config"
+	"github.com/EsteveSegura/BrickHauler/internal/metrics"
+)
+
+// Observe registers o to be told about every measured request and, if it
+// is a metrics.StartObserver, when the measured run starts. It must be
+// called before Run.
+func (r *Runner) Observe(o metrics.Observer) {
+	r.observers = append(r.observers, o)
+}
+
+func (r *Runner) observe(s metrics.Sample) {
+	for _, o := range r.observers {
+		o.Observe(s)
+	}
+}
+
+func (r *Runner) startObservers() {
+	for _, o := range r.observers {
+		if s, ok := o.(metrics.StartObserver); ok {
+			s.Start()
+		}
+	}
+}
+
+// sampleTarget names target in samples. Every replayed request carries its
+// own logged path and query, so they are grouped by a template of the path
+// instead, keeping the names bounded for use as metric labels.
+func (r *Runner) sampleTarget(target *config.Target) string {
+	u := target.URI.URL()
+	if r.cfg.Replay == nil || u == nil {
+		return target.Name
+	}
+	return pathTemplate(u.Path)
+}
+
+// pathTemplate replaces the segments of path that look like IDs with :id,
+// so /users/42/orders and /users/43/orders both become /users/:id/orders.
+func pathTemplate(path string) string {
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if looksLikeID(seg) {
+			segments[i] = ":id"
+		}
+	}
+	if t := strings.Join(segments, "/"); t != "" {
+		return t
+	}
+	return "/"
+}
+
+// looksLikeID reports whether a path segment is a number, a hash, a UUID
+// or a similar value rather than a fixed name: all digits, long, or mixing
+// in digits at some length. Short names such as v2 are kept.
+func looksLikeID(seg string) bool {
+	if seg == "" {
+		return false
+	}
+	digits := strings.IndexFunc(seg, func(r rune) bool { return r < '0' || r > '9' }) == -1
+	hasDigit := strings.ContainsAny(seg, "0123456789")
+	return digits || len(seg) >= 24 || (len(seg) >= 8 && hasDigit)
+}
+
+// classifyError sorts a failed request into one of the metrics error
+// classes.
+func classifyError(err error) string {
+	var (
+		netErr     net.Error
+		dnsErr     *net.DNSError
+		recordErr  tls.RecordHeaderError
+		alertErr   tls.AlertError
+		certErr    *tls.CertificateVerificationError
+		unknownCA  x509.UnknownAuthorityError
+		hostErr    x509.HostnameError
+		invalidErr x509.CertificateInvalidError
+	)
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return metrics.ErrorCanceled
+	case errors.As(err, &dnsErr):
+		return metrics.ErrorDNS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return metrics.ErrorTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return metrics.ErrorRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return metrics.ErrorReset
+	case errors.As(err, &recordErr), errors.As(err, &alertErr), errors.As(err, &certErr), errors.As(err, &unknownCA),
+		errors.As(err, &hostErr), errors.As(err, &invalidErr):
+		return metrics.ErrorTLS
+	}
+	return metrics.ErrorOther
+}
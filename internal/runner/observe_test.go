@@ -0,0 +1,168 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/EsteveSegura/BrickHauler/internal/config"
+	"github.com/EsteveSegura/BrickHauler/internal/metrics"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{context.Canceled, metrics.ErrorCanceled},
+		{fmt.Errorf("get: %w", context.DeadlineExceeded), metrics.ErrorTimeout},
+		{&net.DNSError{Err: "no such host", Name: "nowhere.invalid"}, metrics.ErrorDNS},
+		{&net.OpError{Op: "read", Err: io.ErrUnexpectedEOF}, metrics.ErrorReset},
+		{fmt.Errorf("boom"), metrics.ErrorOther},
+	}
+	for _, tt := range tests {
+		if got := classifyError(tt.err); got != tt.want {
+			t.Errorf("classifyError(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+
+	// A port nobody listens on refuses the connection.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	if _, err := http.Get("http://" + addr); classifyError(err) != metrics.ErrorRefused {
+		t.Errorf("classifyError(%v) = %q, want connection_refused", err, classifyError(err))
+	}
+}
+
+func TestPathTemplate(t *testing.T) {
+	for path, want := range map[string]string{
+		"":                        "/",
+		"/search":                 "/search",
+		"/api/v2/users/42/orders": "/api/v2/users/:id/orders",
+		"/items/3f2a9c1e7b":       "/items/:id",
+		"/u/0b8e6f4c-1d2a-4b3c":   "/u/:id",
+	} {
+		if got := pathTemplate(path); got != want {
+			t.Errorf("pathTemplate(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestSampleTarget(t *testing.T) {
+	uri, _ := config.NewURI("http://a.test/users/42?tab=orders")
+	target := &config.Target{Name: "GET /users/42?tab=orders", Method: config.MethodGET, URI: uri}
+
+	r := &Runner{cfg: &config.Config{}}
+	if got := r.sampleTarget(target); got != target.Name {
+		t.Errorf("sampleTarget() = %q, want the target name", got)
+	}
+	r.cfg.Replay = &config.Replay{}
+	if got := r.sampleTarget(target); got != "/users/:id" {
+		t.Errorf("sampleTarget() for a replay = %q, want /users/:id", got)
+	}
+}
+
+type recorder struct {
+	mu      sync.Mutex
+	samples []metrics.Sample
+}
+
+func (r *recorder) Observe(s metrics.Sample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.samples = append(r.samples, s)
+}
+
+func TestRunner_Observe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}))
+	defer server.Close()
+
+	ok, _ := config.NewURI(server.URL + "/ok")
+	missing, _ := config.NewURI(server.URL + "/missing")
+	cfg := &config.Config{
+		Concurrency: 1,
+		Requests:    2,
+		Sequence:    true,
+		Targets: []config.Target{
+			{Name: "ok", Method: config.MethodGET, URI: ok, Weight: 1},
+			{Name: "missing", Method: config.MethodPOST, URI: missing, Weight: 1},
+		},
+		Warmup: config.Warmup{Requests: 3},
+	}
+
+	r := New(cfg, io.Discard)
+	rec := &recorder{}
+	r.Observe(rec)
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(rec.samples) != 2 {
+		t.Fatalf("observed %d samples, want 2 without the warm-up", len(rec.samples))
+	}
+	got, bad := rec.samples[0], rec.samples[1]
+	if got.Target != "ok" || got.Method != "GET" || got.Status != 200 || got.Failed() || got.Duration < time.Millisecond {
+		t.Errorf("first sample = %+v", got)
+	}
+	if bad.Target != "missing" || bad.Method != "POST" || bad.Status != 404 || bad.Error != metrics.ErrorHTTP {
+		t.Errorf("second sample = %+v", bad)
+	}
+}
+
+// startRecorder notes how many requests the server had seen when the
+// measured run started.
+type startRecorder struct {
+	recorder
+	seen    *int64
+	atStart int64
+	starts  int
+}
+
+func (s *startRecorder) Start() {
+	s.atStart = atomic.LoadInt64(s.seen)
+	s.starts++
+}
+
+func TestRunner_StartObserver(t *testing.T) {
+	var requestCount int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requestCount, 1)
+	}))
+	defer server.Close()
+
+	uri, _ := config.NewURI(server.URL)
+	cfg := &config.Config{
+		URI:         uri,
+		Method:      config.MethodGET,
+		Concurrency: 1,
+		Requests:    2,
+		Warmup:      config.Warmup{Requests: 3},
+	}
+
+	r := New(cfg, io.Discard)
+	rec := &startRecorder{seen: &requestCount}
+	r.Observe(rec)
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.starts != 1 || rec.atStart != 3 {
+		t.Errorf("started %d times after %d requests, want once after the 3 warm-up requests", rec.starts, rec.atStart)
+	}
+}
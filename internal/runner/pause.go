@@ -0,0 +1,155 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// startClock records when the measured run began. For runs limited by
+// Duration, endRun is called once that much unpaused time has passed.
+func (r *Runner) startClock(t time.Time, endRun context.CancelFunc) {
+	r.adjustMu.Lock()
+	defer r.adjustMu.Unlock()
+
+	r.adjust.started = t
+	if r.cfg.Duration > 0 {
+		r.adjust.deadline = t.Add(r.cfg.Duration)
+		r.adjust.end = time.AfterFunc(r.cfg.Duration, endRun)
+	}
+}
+
+// stopClock releases the Duration timer once the run is over.
+func (r *Runner) stopClock() {
+	r.adjustMu.Lock()
+	defer r.adjustMu.Unlock()
+	if r.adjust.end != nil {
+		r.adjust.end.Stop()
+	}
+}
+
+// activeTime returns how long the measured run has been going, leaving
+// out the time spent paused.
+func (r *Runner) activeTime() time.Duration {
+	r.adjustMu.Lock()
+	defer r.adjustMu.Unlock()
+
+	if r.adjust.started.IsZero() {
+		return 0
+	}
+	now := time.Now()
+	active := now.Sub(r.adjust.started) - r.adjust.paused
+	if r.adjust.resume != nil {
+		active -= now.Sub(r.adjust.pausedAt)
+	}
+	return active
+}
+
+// Paused reports whether the run is paused.
+func (r *Runner) Paused() bool {
+	r.adjustMu.Lock()
+	defer r.adjustMu.Unlock()
+	return r.adjust.resume != nil
+}
+
+// Pause stops the virtual users from starting new requests until Resume.
+// Requests in flight finish and connections stay open, but only for as long
+// as idle connections are kept: httpclient.IdleConnTimeout, or less if the
+// server closes them first. The paused time does not count towards Duration
+// or the requests per second. Only adjustable runs can be paused. source
+// says where the pause came from for the results timeline.
+func (r *Runner) Pause(source string) error {
+	if !r.cfg.Adjustable {
+		return ErrNotAdjustable
+	}
+
+	r.adjustMu.Lock()
+	switch {
+	case r.adjust.started.IsZero():
+		r.adjustMu.Unlock()
+		return errors.New("the test has not started yet")
+	case r.adjust.resume != nil:
+		r.adjustMu.Unlock()
+		return errors.New("the test is already paused")
+	}
+	now := time.Now()
+	r.adjust.resume = make(chan struct{})
+	r.adjust.pausedAt = now
+	if r.adjust.end != nil && !r.adjust.end.Stop() {
+		// The time was already up.
+		r.adjust.end = nil
+	}
+	r.adjustMu.Unlock()
+
+	r.logEvent("paused", source)
+	return nil
+}
+
+// Resume lets the virtual users carry on with the rest of the run after
+// Pause. source says where it came from for the results timeline.
+func (r *Runner) Resume(source string) error {
+	if !r.cfg.Adjustable {
+		return ErrNotAdjustable
+	}
+
+	r.adjustMu.Lock()
+	if r.adjust.resume == nil {
+		r.adjustMu.Unlock()
+		return errors.New("the test is not paused")
+	}
+	now := time.Now()
+	pause := now.Sub(r.adjust.pausedAt)
+	r.adjust.paused += pause
+	close(r.adjust.resume)
+	r.adjust.resume = nil
+	if r.adjust.end != nil {
+		r.adjust.deadline = r.adjust.deadline.Add(pause)
+		r.adjust.end.Reset(r.adjust.deadline.Sub(now))
+	}
+	r.adjustMu.Unlock()
+
+	r.logEvent(fmt.Sprintf("resumed after %v", pause.Round(100*time.Millisecond)), source)
+	return nil
+}
+
+// Stop ends the run early as if its time were up: no new requests start,
+// the ones in flight finish, the results are printed and Run returns nil.
+// source says where it came from for the results timeline.
+func (r *Runner) Stop(source string) {
+	r.stopOnce.Do(func() {
+		r.logEvent("stopped", source)
+		close(r.stopped)
+	})
+}
+
+// untilStopped returns a context that ends with parent or on Stop.
+func (r *Runner) untilStopped(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	go func() {
+		select {
+		case <-r.stopped:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// waitResume blocks while the run is paused, returning false if ctx ends
+// first.
+func (r *Runner) waitResume(ctx context.Context) bool {
+	r.adjustMu.Lock()
+	resume := r.adjust.resume
+	r.adjustMu.Unlock()
+
+	if resume == nil {
+		return true
+	}
+	select {
+	case <-resume:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,109 @@
+package runner
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// poolTick is how often the worker pool is resized to follow the load
+// profile.
+const poolTick = 100 * time.Millisecond
+
+// budget is a number of requests shared by virtual users that come and go
+// during the run.
+type budget struct {
+	remaining atomic.Int64
+	// spent is closed once every request has been taken.
+	spent chan struct{}
+}
+
+// newBudget returns a budget of n requests, or nil when the run is only
+// limited by its duration.
+func newBudget(n int) *budget {
+	if n == 0 {
+		return nil
+	}
+	b := &budget{spent: make(chan struct{})}
+	b.remaining.Store(int64(n))
+	return b
+}
+
+// take claims a request, returning false when none are left. A nil budget
+// never runs out.
+func (b *budget) take() bool {
+	if b == nil {
+		return true
+	}
+	n := b.remaining.Add(-1)
+	if n == 0 {
+		close(b.spent)
+	}
+	return n >= 0
+}
+
+// done returns a channel closed once the budget is spent, or nil for a
+// nil budget.
+func (b *budget) done() <-chan struct{} {
+	if b == nil {
+		return nil
+	}
+	return b.spent
+}
+
+// pool starts and stops virtual users to follow the load profile and live
+// adjustments until runCtx ends, the request budget is spent or the profile
+// has finished at zero users. The most recently started users are the first
+// to stop, finishing their request in flight.
+func (r *Runner) pool(ctx, runCtx context.Context) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	var stops []context.CancelFunc
+	defer func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}()
+
+	ticker := time.NewTicker(poolTick)
+	defer ticker.Stop()
+
+	for {
+		elapsed := r.activeTime()
+		if r.profileFinished(elapsed) {
+			return
+		}
+		want := r.wantUsers(elapsed)
+		for len(stops) < want {
+			userCtx, stop := context.WithCancel(runCtx)
+			stops = append(stops, stop)
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				r.worker(ctx, userCtx, id, 0)
+			}(len(stops) - 1)
+		}
+		for len(stops) > want {
+			stops[len(stops)-1]()
+			stops = stops[:len(stops)-1]
+		}
+
+		select {
+		case <-runCtx.Done():
+			return
+		case <-r.budget.done():
+			return
+		case <-ticker.C:
+		case <-r.adjusted:
+		}
+	}
+}
+
+// profileFinished reports whether the load profile has dropped to zero users
+// for good. Adjustable runs never finish this way, as users can be added
+// back.
+func (r *Runner) profileFinished(elapsed time.Duration) bool {
+	return r.cfg.Profile != nil && !r.cfg.Adjustable && r.cfg.Profile.Finished(elapsed)
+}
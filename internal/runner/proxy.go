@@ -0,0 +1,69 @@
+package runner
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/url"
+	"sync/atomic"
+
+	"github.com/EsteveSegura/BrickHauler/internal/config"
+)
+
+// proxyPool hands out proxies to requests according to a rotation strategy.
+type proxyPool struct {
+	proxies  []*url.URL
+	rotation config.ProxyRotation
+	next     atomic.Uint64
+	// labels name each proxy in the per-proxy results.
+	labels map[*url.URL]string
+}
+
+func newProxyPool(proxies []*url.URL, rotation config.ProxyRotation) *proxyPool {
+	if len(proxies) == 0 {
+		return nil
+	}
+	return &proxyPool{proxies: proxies, rotation: rotation, labels: proxyLabels(proxies)}
+}
+
+// proxyLabels names every proxy by its URL with the password hidden,
+// numbering proxies by their position in the list when that alone would
+// not tell them apart, such as the same gateway with different accounts.
+func proxyLabels(proxies []*url.URL) map[*url.URL]string {
+	count := make(map[string]int)
+	for _, u := range proxies {
+		count[u.Redacted()]++
+	}
+
+	labels := make(map[*url.URL]string, len(proxies))
+	for i, u := range proxies {
+		label := u.Redacted()
+		if count[label] > 1 {
+			label = fmt.Sprintf("%s (#%d)", label, i+1)
+		}
+		labels[u] = label
+	}
+	return labels
+}
+
+// label returns the name of a proxy from the pool in the results.
+func (p *proxyPool) label(u *url.URL) string {
+	return p.labels[u]
+}
+
+// pick returns the proxy for the next request of the given worker, or nil
+// when no pool is configured.
+func (p *proxyPool) pick(worker int) *url.URL {
+	if p == nil {
+		return nil
+	}
+
+	switch p.rotation {
+	case config.ProxyRandom:
+		return p.proxies[rand.IntN(len(p.proxies))]
+	case config.ProxySticky:
+		return p.proxies[worker%len(p.proxies)]
+	default:
+		i := p.next.Add(1) - 1
+		return p.proxies[i%uint64(len(p.proxies))]
+	}
+}
@@ -1,10 +1,13 @@
 package runner
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	"io"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/EsteveSegura/BrickHauler/internal/config"
@@ -17,41 +20,164 @@ import (
 // Runner executes load tests.
 type Runner struct {
 	cfg     *config.Config
-	client  *http.Client
+	clients []*http.Client
+	proxies *proxyPool
+	targets *targetPicker
+	limiter *limiter
+	budget  *budget
 	metrics *metrics.Metrics
 	output  *output.Writer
+
+	proxyMetrics  *metrics.Group
+	targetMetrics *metrics.Group
+
+	replayStats output.ReplayStats
+	elapsed     time.Duration
+
+	observers []metrics.Observer
+
+	adjustMu sync.Mutex
+	adjust   adjustments
+	// adjusted wakes the worker pool after a live change.
+	adjusted chan struct{}
+	// stopped is closed by Stop to end the run early.
+	stopped  chan struct{}
+	stopOnce sync.Once
 }
 
 // New creates a new Runner.
 func New(cfg *config.Config, w io.Writer) *Runner {
-	return &Runner{
-		cfg: cfg,
-		client: httpclient.New(httpclient.Config{
-			ProxyURL: cfg.ProxyURL,
-			Timeout:  30 * time.Second,
-		}),
+	r := &Runner{
+		cfg:     cfg,
+		clients: newClients(cfg),
+		proxies: newProxyPool(cfg.Proxies, cfg.ProxyRotation),
+		targets: newTargetPicker(cfg.TargetList(), cfg.Sequence),
+		limiter: newLimiter(cfg.MaxRPS),
 		metrics: metrics.New(cfg.Requests),
 		output:  output.New(w),
+
+		proxyMetrics:  metrics.NewGroup(),
+		targetMetrics: metrics.NewGroup(),
+
+		adjusted: make(chan struct{}, 1),
+		stopped:  make(chan struct{}),
+	}
+	if cfg.Profile != nil || cfg.Adjustable {
+		// Users come and go, so they draw from a shared budget rather
+		// than a fixed share of the requests each.
+		r.budget = newBudget(cfg.Requests)
+	}
+	if cfg.Adjustable && r.limiter == nil {
+		// Unlimited until a rate is set during the run.
+		r.limiter = &limiter{}
+	}
+	return r
+}
+
+// newClients creates the HTTP clients used by the workers: a single shared
+// client, one per source IP, or one per worker when connections are
+// isolated. Workers are spread across source IPs in turn.
+func newClients(cfg *config.Config) []*http.Client {
+	hc := httpclient.Config{
+		Timeout:                   cfg.Transport.Timeout,
+		ConnectTimeout:            cfg.Transport.ConnectTimeout,
+		TLSHandshakeTimeout:       cfg.Transport.TLSHandshakeTimeout,
+		ResponseHeaderTimeout:     cfg.Transport.ResponseHeaderTimeout,
+		MaxIdleConns:              cfg.IdlePoolSize(),
+		MaxConnsPerHost:           cfg.PoolSize(),
+		DisableKeepAlives:         cfg.Transport.DisableKeepAlives,
+		TLSConfig:                 cfg.TLSConfig,
+		DNSCacheTTL:               cfg.Transport.DNSCacheTTL,
+		Resolve:                   httpclient.NewResolveTable(cfg.Resolve),
+		UnixSocket:                cfg.SocketPath(),
+		HTTPVersion:               string(cfg.HTTPVersion),
+		HTTP2MaxReadFrame:         cfg.HTTP2.MaxReadFrameSize,
+		HTTP2MaxReceiveBufStream:  cfg.HTTP2.MaxReceiveBufferPerStream,
+		HTTP2MaxReceiveBufConnect: cfg.HTTP2.MaxReceiveBufferPerConnection,
+	}
+
+	if !cfg.IsolateConnections {
+		if len(cfg.SourceIPs) == 0 {
+			return []*http.Client{httpclient.New(hc)}
+		}
+		clients := make([]*http.Client, len(cfg.SourceIPs))
+		for i, ip := range cfg.SourceIPs {
+			hc.SourceIP = ip
+			clients[i] = httpclient.New(hc)
+		}
+		return clients
+	}
+
+	// A virtual user sends one request at a time, so it only ever needs a
+	// single idle connection per host unless told otherwise.
+	hc.MaxIdleConns = cfg.Transport.MaxIdleConns
+	if hc.MaxIdleConns == 0 {
+		hc.MaxIdleConns = 1
+	}
+	hc.MaxConnsPerHost = cfg.Transport.MaxConnsPerHost
+
+	clients := make([]*http.Client, cfg.Concurrency)
+	for i := range clients {
+		if len(cfg.SourceIPs) > 0 {
+			hc.SourceIP = cfg.SourceIPs[i%len(cfg.SourceIPs)]
+		}
+		clients[i] = httpclient.New(hc)
 	}
+	return clients
+}
+
+// clientFor returns the HTTP client used by the given worker.
+func (r *Runner) clientFor(worker int) *http.Client {
+	return r.clients[worker%len(r.clients)]
 }
 
 // Run executes the load test with graceful shutdown support.
 func (r *Runner) Run(ctx context.Context) error {
-	startTime := time.Now()
-
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	if !r.cfg.Warmup.IsZero() {
+		if r.cfg.LiveFeed {
+			r.output.PrintWarmup(r.cfg.Warmup)
+		}
+		r.warmUp(ctx)
+	}
+
+	// The clock starts after the warm-up so it does not dilute the RPS.
+	startTime := time.Now()
+	r.startObservers()
+
+	// Once the run's duration is up, workers stop starting requests but
+	// let the ones in flight finish.
+	runCtx, endRun := r.untilStopped(ctx)
+	defer endRun()
+	r.startClock(startTime, endRun)
+	defer r.stopClock()
+
 	var wg sync.WaitGroup
 	requestsPerWorker := r.cfg.RequestsPerWorker()
 
-	// Launch worker goroutines
-	for i := 0; i < r.cfg.Concurrency; i++ {
+	if r.cfg.Replay != nil {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			r.worker(ctx, requestsPerWorker)
+			r.replay(ctx, runCtx, startTime)
 		}()
+	} else if r.cfg.Profile != nil || r.cfg.Adjustable {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.pool(ctx, runCtx)
+		}()
+	} else {
+		// Launch worker goroutines
+		for i := 0; i < r.cfg.Concurrency; i++ {
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				r.worker(ctx, runCtx, id, requestsPerWorker)
+			}(i)
+		}
 	}
 
 	// Progress reporting goroutine for live feed
@@ -60,7 +186,7 @@ func (r *Runner) Run(ctx context.Context) error {
 		progressWg.Add(1)
 		go func() {
 			defer progressWg.Done()
-			r.progressReporter(ctx, startTime)
+			r.progressReporter(ctx)
 		}()
 	}
 
@@ -90,61 +216,315 @@ func (r *Runner) Run(ctx context.Context) error {
 		r.output.PrintNewline()
 	}
 
-	duration := time.Since(startTime)
+	duration := r.activeTime()
+	r.elapsed = duration
 	r.output.PrintResults(r.cfg, r.metrics.Snapshot(), duration)
+	if r.cfg.Replay != nil {
+		r.output.PrintReplay(r.replayStats)
+	}
+	if stats := r.limiter.snapshot(); stats.MaxRPS > 0 {
+		r.output.PrintRateLimit(stats)
+	}
+	if timeline := r.Timeline(); len(timeline) > 0 {
+		r.output.PrintTimeline(timeline)
+	}
+	if len(r.cfg.Targets) > 0 {
+		r.output.PrintBreakdown("Target", r.targetMetrics.Snapshot())
+	}
+	if r.proxies != nil {
+		r.output.PrintBreakdown("Proxy", r.proxyMetrics.Snapshot())
+	}
 
 	return err
 }
 
-// worker sends requests for a single virtual user.
-func (r *Runner) worker(ctx context.Context, numRequests int) {
-	for i := 0; i < numRequests; i++ {
+// Result returns the metrics of the finished run and how long it took.
+func (r *Runner) Result() (metrics.Snapshot, time.Duration) {
+	return r.metrics.Snapshot(), r.elapsed
+}
+
+// Progress returns the metrics so far and how long the run has been going,
+// pauses excluded, while it runs.
+func (r *Runner) Progress() (metrics.Snapshot, time.Duration) {
+	return r.metrics.Snapshot(), r.activeTime()
+}
+
+// worker sends requests for a single virtual user, numRequests of them or,
+// when zero, until runCtx ends or the shared budget is spent. Requests are
+// sent under ctx so the last ones can finish after runCtx ends.
+func (r *Runner) worker(ctx, runCtx context.Context, id, numRequests int) {
+	iterationStart := time.Now()
+	for i := 0; numRequests == 0 || i < numRequests; i++ {
 		select {
-		case <-ctx.Done():
-			return // Graceful shutdown
+		case <-runCtx.Done():
+			return // Graceful shutdown or end of the run's duration
 		default:
-			r.sendRequest(ctx)
 		}
+
+		if !r.waitResume(runCtx) || !r.budget.take() || !r.limiter.wait(runCtx) {
+			return
+		}
+		target := r.targets.next(i)
+		r.sendRequest(ctx, id, target)
+
+		if i == numRequests-1 {
+			return
+		}
+
+		pause := r.thinkTime(target)
+		if r.cfg.Pacing > 0 && (i+1)%r.targets.iterationLength() == 0 {
+			iterationStart = nextIteration(iterationStart, r.cfg.Pacing, time.Now())
+			pause = time.Until(iterationStart)
+		}
+		if pause > 0 && !sleep(runCtx, pause) {
+			return
+		}
+	}
+}
+
+// nextIteration returns when the iteration after one that began at start
+// should begin under the given pacing. Iterations that overrun the pacing
+// start the next one at once.
+func nextIteration(start time.Time, pacing time.Duration, now time.Time) time.Time {
+	next := start.Add(pacing)
+	if next.Before(now) {
+		return now
+	}
+	return next
+}
+
+// warmUp sends the configured warm-up traffic without recording it, to
+// fill connection pools and caches before the measured run. Virtual users
+// share the request budget and keep their think time but not their pacing.
+func (r *Runner) warmUp(ctx context.Context) {
+	warmCtx, stop := r.untilStopped(ctx)
+	defer stop()
+	if r.cfg.Warmup.Duration > 0 {
+		var cancel context.CancelFunc
+		warmCtx, cancel = context.WithTimeout(warmCtx, r.cfg.Warmup.Duration)
+		defer cancel()
+	}
+
+	budget := int64(r.cfg.Warmup.Requests)
+	var sent atomic.Int64
+
+	users := r.cfg.Concurrency
+	if r.cfg.Profile != nil {
+		users = max(r.cfg.Profile.Users(0), 1)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < users; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for step := 0; warmCtx.Err() == nil; step++ {
+				if budget > 0 && sent.Add(1) > budget {
+					return
+				}
+				if !r.limiter.wait(warmCtx) {
+					return
+				}
+				target := r.targets.next(step)
+				r.warmRequest(ctx, id, target)
+				if pause := r.thinkTime(target); pause > 0 && !sleep(warmCtx, pause) {
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	r.limiter.resetStats()
+}
+
+// thinkTime returns the pause after a request to target: its own think
+// time, or a draw from the run's distribution.
+func (r *Runner) thinkTime(target *config.Target) time.Duration {
+	if target.ThinkTime > 0 {
+		return target.ThinkTime
+	}
+	return r.cfg.ThinkTime.Sample()
+}
+
+// replayTolerance is how far behind schedule a replayed request may go out
+// before it is reported as late.
+const replayTolerance = 10 * time.Millisecond
+
+// replay sends the recorded requests on their original schedule. The
+// workers bound the requests in flight: when all of them are busy, the
+// next request goes out late and the delay is reported.
+func (r *Runner) replay(ctx, runCtx context.Context, start time.Time) {
+	jobs := make(chan *config.Target)
+
+	var wg sync.WaitGroup
+	for i := 0; i < r.cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for t := range jobs {
+				if r.limiter.wait(runCtx) {
+					r.sendRequest(ctx, id, t)
+				}
+			}
+		}(i)
+	}
+	defer func() {
+		close(jobs)
+		wg.Wait()
+	}()
+
+	replay := r.cfg.Replay
+	for i := range replay.Requests {
+		req := &replay.Requests[i]
+		due := start.Add(replay.At(*req))
+		if !sleep(runCtx, time.Until(due)) {
+			return
+		}
+
+		select {
+		case jobs <- &req.Target:
+		case <-runCtx.Done():
+			return
+		}
+		if lag := time.Since(due); lag > replayTolerance {
+			r.replayStats.Late++
+			r.replayStats.MaxLag = max(r.replayStats.MaxLag, lag)
+		}
+	}
+}
+
+// sleep pauses for d, returning false if ctx is cancelled first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
 	}
 }
 
 // sendRequest sends a single HTTP request and records metrics.
-func (r *Runner) sendRequest(ctx context.Context) {
-	start := time.Now()
+func (r *Runner) sendRequest(ctx context.Context, worker int, target *config.Target) {
+	stats := collectors{r.metrics}
+	if len(r.cfg.Targets) > 0 {
+		stats = append(stats, r.targetMetrics.Get(target.Name))
+	}
+	if proxy := r.proxies.pick(worker); proxy != nil {
+		ctx = httpclient.WithProxy(ctx, proxy)
+		stats = append(stats, r.proxyMetrics.Get(r.proxies.label(proxy)))
+	}
+
+	resp, duration, err := r.exchange(ctx, worker, target)
+	sample := metrics.Sample{Target: r.sampleTarget(target), Method: string(target.Method), Duration: duration}
+	defer func() { r.observe(sample) }()
 
-	req, err := http.NewRequestWithContext(ctx, r.cfg.Method.String(), r.cfg.URI.String(), nil)
 	if err != nil {
-		r.metrics.RecordFailure()
+		sample.Error = classifyError(err)
+		stats.failure()
 		return
 	}
+	sample.Status = resp.StatusCode
 
-	req.Header.Set("User-Agent", version.UserAgent)
+	r.metrics.RecordProtocol(resp.Proto)
+	if resp.TLS != nil {
+		r.metrics.RecordTLS(tls.VersionName(resp.TLS.Version), tls.CipherSuiteName(resp.TLS.CipherSuite))
+	}
+
+	if resp.StatusCode < 400 {
+		stats.success(duration)
+	} else {
+		sample.Error = metrics.ErrorHTTP
+		stats.failure()
+	}
+}
+
+// warmRequest sends a single HTTP request without recording it.
+func (r *Runner) warmRequest(ctx context.Context, worker int, target *config.Target) {
+	if proxy := r.proxies.pick(worker); proxy != nil {
+		ctx = httpclient.WithProxy(ctx, proxy)
+	}
+	r.exchange(ctx, worker, target)
+}
+
+// exchange sends a request for target and reads the whole response,
+// returning it with the time it took.
+func (r *Runner) exchange(ctx context.Context, worker int, target *config.Target) (*http.Response, time.Duration, error) {
+	start := time.Now()
+
+	req, err := newRequest(ctx, target, r.cfg.Headers)
+	if err != nil {
+		return nil, 0, err
+	}
 
 	for _, cookie := range r.cfg.Cookies {
 		req.AddCookie(cookie)
 	}
+	for _, cookie := range target.Cookies {
+		req.AddCookie(cookie)
+	}
 
-	resp, err := r.client.Do(req)
+	resp, err := r.clientFor(worker).Do(req)
 	if err != nil {
-		r.metrics.RecordFailure()
-		return
+		return nil, time.Since(start), err
 	}
 	defer resp.Body.Close()
 
 	// Drain body to enable connection reuse
 	io.Copy(io.Discard, resp.Body)
 
-	duration := time.Since(start)
+	return resp, time.Since(start), nil
+}
 
-	if resp.StatusCode < 400 {
-		r.metrics.RecordSuccess(duration)
-	} else {
-		r.metrics.RecordFailure()
+// newRequest builds the HTTP request for a target. Target headers take
+// precedence over the run-wide headers.
+func newRequest(ctx context.Context, t *config.Target, headers http.Header) (*http.Request, error) {
+	var body io.Reader
+	if len(t.Body) > 0 {
+		body = bytes.NewReader(t.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, t.Method.String(), t.URI.RequestURL(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", version.UserAgent)
+	for _, h := range []http.Header{headers, t.Headers} {
+		for name, values := range h {
+			req.Header[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+
+	// Go sends the Host header from req.Host rather than the header map.
+	if host := req.Header.Get("Host"); host != "" {
+		req.Host = host
+	}
+
+	return req, nil
+}
+
+// collectors records a request outcome into several Metrics at once, such
+// as the run totals and the breakdown for the proxy used.
+type collectors []*metrics.Metrics
+
+func (c collectors) success(d time.Duration) {
+	for _, m := range c {
+		m.RecordSuccess(d)
+	}
+}
+
+func (c collectors) failure() {
+	for _, m := range c {
+		m.RecordFailure()
 	}
 }
 
 // progressReporter periodically prints progress.
-func (r *Runner) progressReporter(ctx context.Context, startTime time.Time) {
+func (r *Runner) progressReporter(ctx context.Context) {
 	ticker := time.NewTicker(100 * time.Millisecond)
 	defer ticker.Stop()
 
@@ -154,7 +534,7 @@ func (r *Runner) progressReporter(ctx context.Context, startTime time.Time) {
 			return
 		case <-ticker.C:
 			snap := r.metrics.Snapshot()
-			r.output.PrintProgress(snap.TotalRequests(), int64(r.cfg.Requests), time.Since(startTime))
+			r.output.PrintProgress(snap.TotalRequests(), int64(r.cfg.Requests), r.activeTime())
 		}
 	}
 }
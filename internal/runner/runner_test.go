@@ -2,9 +2,17 @@ package runner
 
 import (
 	"context"
+	"crypto/tls"
+	"encoding/binary"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -196,3 +204,1140 @@ func TestRunner_FailedRequests(t *testing.T) {
 		t.Errorf("SuccessCount = %d, want 0", snap.SuccessCount)
 	}
 }
+
+func TestRunner_RequestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	uri, err := config.NewURI(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create URI: %v", err)
+	}
+
+	cfg := &config.Config{
+		URI:         uri,
+		Method:      config.MethodGET,
+		Concurrency: 2,
+		Requests:    2,
+		Transport:   config.Transport{Timeout: 50 * time.Millisecond},
+	}
+
+	r := New(cfg, io.Discard)
+	_ = r.Run(context.Background())
+
+	snap := r.metrics.Snapshot()
+	if snap.FailureCount != 2 {
+		t.Errorf("FailureCount = %d, want 2", snap.FailureCount)
+	}
+}
+
+func TestRunner_TLSInsecure(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	uri, err := config.NewURI(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create URI: %v", err)
+	}
+
+	cfg := &config.Config{
+		URI:         uri,
+		Method:      config.MethodGET,
+		Concurrency: 1,
+		Requests:    2,
+		TLSConfig:   &tls.Config{InsecureSkipVerify: true, MaxVersion: tls.VersionTLS12},
+	}
+
+	r := New(cfg, io.Discard)
+	_ = r.Run(context.Background())
+
+	snap := r.metrics.Snapshot()
+	if snap.SuccessCount != 2 {
+		t.Errorf("SuccessCount = %d, want 2", snap.SuccessCount)
+	}
+	if snap.TLSVersions["TLS 1.2"] != 2 {
+		t.Errorf("TLSVersions = %v, want 2 x TLS 1.2", snap.TLSVersions)
+	}
+}
+
+func TestRunner_HTTP2Negotiated(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	uri, err := config.NewURI(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create URI: %v", err)
+	}
+
+	cfg := &config.Config{
+		URI:         uri,
+		Method:      config.MethodGET,
+		Concurrency: 1,
+		Requests:    2,
+		TLSConfig:   &tls.Config{InsecureSkipVerify: true},
+	}
+
+	r := New(cfg, io.Discard)
+	_ = r.Run(context.Background())
+
+	snap := r.metrics.Snapshot()
+	if snap.Protocols["HTTP/2.0"] != 2 {
+		t.Errorf("Protocols = %v, want 2 x HTTP/2.0", snap.Protocols)
+	}
+}
+
+func TestRunner_H2C(t *testing.T) {
+	var proto atomic.Value
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proto.Store(r.Proto)
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.Config.Protocols = new(http.Protocols)
+	server.Config.Protocols.SetHTTP1(true)
+	server.Config.Protocols.SetUnencryptedHTTP2(true)
+	server.Start()
+	defer server.Close()
+
+	uri, err := config.NewURI(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create URI: %v", err)
+	}
+
+	cfg := &config.Config{
+		URI:         uri,
+		Method:      config.MethodGET,
+		Concurrency: 1,
+		Requests:    1,
+		HTTPVersion: config.HTTPVersionH2C,
+	}
+
+	r := New(cfg, io.Discard)
+	_ = r.Run(context.Background())
+
+	if got := proto.Load(); got != "HTTP/2.0" {
+		t.Errorf("server saw protocol %v, want HTTP/2.0", got)
+	}
+}
+
+func TestRunner_IsolateConnections(t *testing.T) {
+	var newConns int64
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(&newConns, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	uri, err := config.NewURI(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create URI: %v", err)
+	}
+
+	cfg := &config.Config{
+		URI:                uri,
+		Method:             config.MethodGET,
+		Concurrency:        4,
+		Requests:           40,
+		IsolateConnections: true,
+	}
+
+	r := New(cfg, io.Discard)
+	if len(r.clients) != 4 {
+		t.Fatalf("got %d clients, want 4", len(r.clients))
+	}
+	_ = r.Run(context.Background())
+
+	if got := atomic.LoadInt64(&newConns); got != 4 {
+		t.Errorf("server saw %d connections, want 4", got)
+	}
+}
+
+func TestRunner_Resolve(t *testing.T) {
+	var host atomic.Value
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host.Store(r.Host)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	uri, err := config.NewURI("http://brickhauler.invalid:" + port + "/")
+	if err != nil {
+		t.Fatalf("failed to create URI: %v", err)
+	}
+
+	cfg := &config.Config{
+		URI:         uri,
+		Method:      config.MethodGET,
+		Concurrency: 1,
+		Requests:    2,
+		Resolve:     map[string][]string{"brickhauler.invalid:" + port: {"127.0.0.1"}},
+	}
+
+	r := New(cfg, io.Discard)
+	_ = r.Run(context.Background())
+
+	if snap := r.metrics.Snapshot(); snap.SuccessCount != 2 {
+		t.Errorf("SuccessCount = %d, want 2", snap.SuccessCount)
+	}
+	if got := host.Load(); got != "brickhauler.invalid:"+port {
+		t.Errorf("Host header = %v, want brickhauler.invalid:%s", got, port)
+	}
+}
+
+func TestRunner_UnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "app.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+
+	var path atomic.Value
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path.Store(r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	uri, err := config.NewURI("unix://" + socket + ":/health")
+	if err != nil {
+		t.Fatalf("failed to create URI: %v", err)
+	}
+
+	cfg := &config.Config{
+		URI:         uri,
+		Method:      config.MethodGET,
+		Concurrency: 1,
+		Requests:    2,
+	}
+
+	r := New(cfg, io.Discard)
+	_ = r.Run(context.Background())
+
+	if snap := r.metrics.Snapshot(); snap.SuccessCount != 2 {
+		t.Errorf("SuccessCount = %d, want 2", snap.SuccessCount)
+	}
+	if got := path.Load(); got != "/health" {
+		t.Errorf("path = %v, want /health", got)
+	}
+}
+
+func TestRunner_HTTPProxyAuthorization(t *testing.T) {
+	var proxyAuth atomic.Value
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxyAuth.Store(r.Header.Get("Proxy-Authorization"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+
+	uri, err := config.NewURI("http://target.invalid/")
+	if err != nil {
+		t.Fatalf("failed to create URI: %v", err)
+	}
+	proxyURL, err := config.ParseProxyURL(proxy.URL, "alice:secret")
+	if err != nil {
+		t.Fatalf("failed to parse proxy: %v", err)
+	}
+
+	cfg := &config.Config{
+		URI:         uri,
+		Method:      config.MethodGET,
+		Concurrency: 1,
+		Requests:    1,
+		Proxies:     []*url.URL{proxyURL},
+	}
+
+	r := New(cfg, io.Discard)
+	_ = r.Run(context.Background())
+
+	if got := proxyAuth.Load(); got != "Basic YWxpY2U6c2VjcmV0" {
+		t.Errorf("Proxy-Authorization = %v, want Basic YWxpY2U6c2VjcmV0", got)
+	}
+}
+
+func TestRunner_SOCKS5Proxy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	socks, creds := startSOCKS5(t)
+
+	uri, err := config.NewURI(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create URI: %v", err)
+	}
+
+	cfg := &config.Config{
+		URI:         uri,
+		Method:      config.MethodGET,
+		Concurrency: 1,
+		Requests:    2,
+		Proxies:     []*url.URL{{Scheme: "socks5h", Host: socks, User: url.UserPassword("alice", "secret")}},
+	}
+
+	r := New(cfg, io.Discard)
+	_ = r.Run(context.Background())
+
+	if snap := r.metrics.Snapshot(); snap.SuccessCount != 2 {
+		t.Errorf("SuccessCount = %d, want 2", snap.SuccessCount)
+	}
+	if got := creds.Load(); got != "alice:secret" {
+		t.Errorf("SOCKS5 credentials = %v, want alice:secret", got)
+	}
+}
+
+// startSOCKS5 runs a minimal SOCKS5 server with username/password
+// authentication and returns its address and the last credentials seen.
+func startSOCKS5(t *testing.T) (string, *atomic.Value) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	creds := new(atomic.Value)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveSOCKS5(conn, creds)
+		}
+	}()
+
+	return ln.Addr().String(), creds
+}
+
+func serveSOCKS5(conn net.Conn, creds *atomic.Value) {
+	defer conn.Close()
+
+	readN := func(n int) []byte {
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return nil
+		}
+		return buf
+	}
+
+	// Greeting: version, method count, methods. Pick username/password.
+	hdr := readN(2)
+	if hdr == nil || readN(int(hdr[1])) == nil {
+		return
+	}
+	conn.Write([]byte{5, 2})
+
+	// RFC 1929 sub-negotiation.
+	ver := readN(2)
+	if ver == nil {
+		return
+	}
+	user := readN(int(ver[1]))
+	plen := readN(1)
+	if user == nil || plen == nil {
+		return
+	}
+	pass := readN(int(plen[0]))
+	creds.Store(string(user) + ":" + string(pass))
+	conn.Write([]byte{1, 0})
+
+	// CONNECT request.
+	req := readN(4)
+	if req == nil {
+		return
+	}
+	var host string
+	switch req[3] {
+	case 1:
+		host = net.IP(readN(4)).String()
+	case 3:
+		host = string(readN(int(readN(1)[0])))
+	case 4:
+		host = net.IP(readN(16)).String()
+	}
+	port := binary.BigEndian.Uint16(readN(2))
+
+	upstream, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+	if err != nil {
+		conn.Write([]byte{5, 1, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer upstream.Close()
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+
+	go io.Copy(upstream, conn)
+	io.Copy(conn, upstream)
+}
+
+func TestRunner_ProxyRotation(t *testing.T) {
+	var hits [2]int64
+	proxies := make([]*url.URL, 2)
+	for i := range proxies {
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt64(&hits[i], 1)
+			if i == 1 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer proxy.Close()
+		proxies[i], _ = url.Parse(proxy.URL)
+	}
+
+	uri, err := config.NewURI("http://target.invalid/")
+	if err != nil {
+		t.Fatalf("failed to create URI: %v", err)
+	}
+
+	cfg := &config.Config{
+		URI:           uri,
+		Method:        config.MethodGET,
+		Concurrency:   2,
+		Requests:      10,
+		Proxies:       proxies,
+		ProxyRotation: config.ProxyRoundRobin,
+	}
+
+	r := New(cfg, io.Discard)
+	_ = r.Run(context.Background())
+
+	if hits[0] != 5 || hits[1] != 5 {
+		t.Errorf("proxy hits = %v, want 5 each", hits)
+	}
+
+	perProxy := r.proxyMetrics.Snapshot()
+	if got := perProxy[proxies[0].Redacted()].SuccessCount; got != 5 {
+		t.Errorf("successes via first proxy = %d, want 5", got)
+	}
+	if got := perProxy[proxies[1].Redacted()].FailureCount; got != 5 {
+		t.Errorf("failures via second proxy = %d, want 5", got)
+	}
+}
+
+func TestRunner_SourceIPs(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		peers = map[string]int{}
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		mu.Lock()
+		peers[host]++
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// 127.0.0.2 is only routable on systems that assign all of 127/8 to lo.
+	probe := net.Dialer{LocalAddr: &net.TCPAddr{IP: net.ParseIP("127.0.0.2")}}
+	conn, err := probe.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Skipf("cannot bind to 127.0.0.2: %v", err)
+	}
+	conn.Close()
+
+	uri, err := config.NewURI(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create URI: %v", err)
+	}
+
+	cfg := &config.Config{
+		URI:         uri,
+		Method:      config.MethodGET,
+		Concurrency: 4,
+		Requests:    8,
+		SourceIPs:   []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("127.0.0.2")},
+	}
+
+	r := New(cfg, io.Discard)
+	_ = r.Run(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+	if peers["127.0.0.1"] != 4 || peers["127.0.0.2"] != 4 {
+		t.Errorf("requests per source IP = %v, want 4 each", peers)
+	}
+}
+
+func TestRunner_WeightedTargets(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		paths  = map[string]int{}
+		bodies = map[string]string{}
+		tokens = map[string]string{}
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		paths[r.Method+" "+r.URL.Path]++
+		bodies[r.URL.Path] = string(body)
+		tokens[r.URL.Path] = r.Header.Get("Authorization")
+		mu.Unlock()
+		if r.URL.Path == "/checkout" {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	target := func(name string, method config.HTTPMethod, path string, weight int) config.Target {
+		uri, err := config.NewURI(server.URL + path)
+		if err != nil {
+			t.Fatalf("failed to create URI: %v", err)
+		}
+		return config.Target{Name: name, Method: method, URI: uri, Weight: weight}
+	}
+
+	checkout := target("checkout", config.MethodPOST, "/checkout", 1)
+	checkout.Body = []byte(`{"cart":1}`)
+	checkout.Headers = http.Header{"Authorization": {"Bearer checkout"}}
+
+	cfg := &config.Config{
+		Concurrency: 2,
+		Requests:    20,
+		Headers:     http.Header{"Authorization": {"Bearer global"}},
+		Targets: []config.Target{
+			target("search", config.MethodGET, "/search", 7),
+			target("item", config.MethodGET, "/item/42", 2),
+			checkout,
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("invalid config: %v", err)
+	}
+
+	r := New(cfg, io.Discard)
+	_ = r.Run(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+	if paths["GET /search"] != 14 || paths["GET /item/42"] != 4 || paths["POST /checkout"] != 2 {
+		t.Errorf("requests per target = %v, want 14/4/2", paths)
+	}
+	if bodies["/checkout"] != `{"cart":1}` {
+		t.Errorf("checkout body = %q", bodies["/checkout"])
+	}
+	if tokens["/search"] != "Bearer global" || tokens["/checkout"] != "Bearer checkout" {
+		t.Errorf("Authorization headers = %v", tokens)
+	}
+
+	perTarget := r.targetMetrics.Snapshot()
+	if perTarget["search"].SuccessCount != 14 {
+		t.Errorf("search successes = %d, want 14", perTarget["search"].SuccessCount)
+	}
+	if perTarget["checkout"].FailureCount != 2 {
+		t.Errorf("checkout failures = %d, want 2", perTarget["checkout"].FailureCount)
+	}
+}
+
+func TestRunner_SequenceWithThinkTime(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, _ := r.Cookie("sid")
+		mu.Lock()
+		entry := r.URL.Path
+		if cookie != nil {
+			entry += " sid=" + cookie.Value
+		}
+		order = append(order, entry)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	login, _ := config.NewURI(server.URL + "/login")
+	cart, _ := config.NewURI(server.URL + "/cart")
+
+	cfg := &config.Config{
+		Concurrency: 1,
+		Requests:    3,
+		Sequence:    true,
+		Targets: []config.Target{
+			{Name: "login", Method: config.MethodGET, URI: login, Weight: 1, ThinkTime: 50 * time.Millisecond},
+			{Name: "cart", Method: config.MethodGET, URI: cart, Weight: 1,
+				Cookies: []*http.Cookie{{Name: "sid", Value: "abc"}}},
+		},
+	}
+
+	r := New(cfg, io.Discard)
+	start := time.Now()
+	_ = r.Run(context.Background())
+
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("run took %v, want at least the 50ms think time", elapsed)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"/login", "/cart sid=abc", "/login"}
+	if len(order) != len(want) {
+		t.Fatalf("requests = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Errorf("requests = %v, want %v", order, want)
+			break
+		}
+	}
+}
+
+func TestRunner_Replay(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		arrivals = map[string]time.Time{}
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		arrivals[r.Method+" "+r.URL.Path] = time.Now()
+		mu.Unlock()
+		if r.URL.Path == "/slow" {
+			time.Sleep(100 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	base, _ := config.NewURI(server.URL)
+	request := func(offset time.Duration, method config.HTTPMethod, path string) config.ReplayRequest {
+		uri, _ := base.WithPath(path)
+		return config.ReplayRequest{
+			Offset: offset,
+			Target: config.Target{Name: path, Method: method, URI: uri, Weight: 1},
+		}
+	}
+
+	// At 2x the recorded 200ms gap shrinks to 100ms. With one worker the
+	// third request waits for the slow second one and goes out late.
+	cfg := &config.Config{
+		URI:         base,
+		Concurrency: 1,
+		Requests:    3,
+		Replay: &config.Replay{
+			Speed: 2,
+			Requests: []config.ReplayRequest{
+				request(0, config.MethodGET, "/first"),
+				request(200*time.Millisecond, config.MethodPOST, "/slow"),
+				request(220*time.Millisecond, config.MethodGET, "/last"),
+			},
+		},
+	}
+
+	r := New(cfg, io.Discard)
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(arrivals) != 3 {
+		t.Fatalf("got requests %v, want 3", arrivals)
+	}
+	if gap := arrivals["POST /slow"].Sub(arrivals["GET /first"]); gap < 90*time.Millisecond || gap > 190*time.Millisecond {
+		t.Errorf("gap between replayed requests = %v, want about 100ms", gap)
+	}
+
+	if r.replayStats.Late != 1 || r.replayStats.MaxLag < 50*time.Millisecond {
+		t.Errorf("replay stats = %+v, want one late request", r.replayStats)
+	}
+	if snap := r.metrics.Snapshot(); snap.SuccessCount != 3 {
+		t.Errorf("successful requests = %d, want 3", snap.SuccessCount)
+	}
+}
+
+func TestNextIteration(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	pacing := 50 * time.Millisecond
+
+	// An iteration that finished early waits for the pacing.
+	if got := nextIteration(start, pacing, start.Add(20*time.Millisecond)); !got.Equal(start.Add(pacing)) {
+		t.Errorf("after a short iteration, next = %v, want %v", got, start.Add(pacing))
+	}
+	// An iteration that overran starts the next one at once, without trying
+	// to catch up.
+	late := start.Add(80 * time.Millisecond)
+	if got := nextIteration(start, pacing, late); !got.Equal(late) {
+		t.Errorf("after an overrun, next = %v, want %v", got, late)
+	}
+}
+
+func TestRunner_ThinkTimeAndPacing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	uri, _ := config.NewURI(server.URL)
+	thinkTime, _ := config.ParseThinkTime("20ms-30ms")
+
+	// Pauses never end early, so the run takes at least min. The ceiling
+	// only catches a run that hangs, as a loaded machine can be much slower.
+	const ceiling = 5 * time.Second
+	tests := []struct {
+		name string
+		cfg  config.Config
+		min  time.Duration
+	}{
+		// Three pauses between four requests.
+		{"think time", config.Config{ThinkTime: thinkTime}, 60 * time.Millisecond},
+		// Four iterations starting every 50ms; the pacing replaces the pause.
+		{"pacing", config.Config{ThinkTime: thinkTime, Pacing: 50 * time.Millisecond}, 150 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.URI = uri
+			cfg.Method = config.MethodGET
+			cfg.Concurrency = 2
+			cfg.Requests = 8
+
+			r := New(&cfg, io.Discard)
+			start := time.Now()
+			if err := r.Run(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed < tt.min || elapsed > ceiling {
+				t.Errorf("run took %v, want at least %v", elapsed, tt.min)
+			}
+		})
+	}
+}
+
+func TestRunner_Duration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	uri, _ := config.NewURI(server.URL)
+	cfg := &config.Config{
+		URI:         uri,
+		Method:      config.MethodGET,
+		Concurrency: 2,
+		Duration:    100 * time.Millisecond,
+	}
+
+	r := New(cfg, io.Discard)
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	snap, elapsed := r.Result()
+	if elapsed < 100*time.Millisecond || elapsed > 400*time.Millisecond {
+		t.Errorf("run took %v, want about 100ms", elapsed)
+	}
+	// Requests in flight when the time is up finish instead of failing.
+	if snap.FailureCount != 0 || snap.SuccessCount < 4 {
+		t.Errorf("successful = %d, failed = %d; want several successes and no failures",
+			snap.SuccessCount, snap.FailureCount)
+	}
+}
+
+func TestRunner_Warmup(t *testing.T) {
+	var requestCount int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requestCount, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	uri, _ := config.NewURI(server.URL)
+	cfg := &config.Config{
+		URI:         uri,
+		Method:      config.MethodGET,
+		Concurrency: 3,
+		Requests:    9,
+		Warmup:      config.Warmup{Requests: 5},
+	}
+
+	r := New(cfg, io.Discard)
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := atomic.LoadInt64(&requestCount); got != 14 {
+		t.Errorf("server saw %d requests, want 5 warm-up and 9 measured", got)
+	}
+	if snap, _ := r.Result(); snap.TotalRequests() != 9 {
+		t.Errorf("recorded %d requests, want only the 9 measured", snap.TotalRequests())
+	}
+}
+
+func TestRunner_WarmupDurationNotTimed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	uri, _ := config.NewURI(server.URL)
+	cfg := &config.Config{
+		URI:         uri,
+		Method:      config.MethodGET,
+		Concurrency: 1,
+		Requests:    2,
+		Warmup:      config.Warmup{Duration: 200 * time.Millisecond},
+	}
+
+	start := time.Now()
+	r := New(cfg, io.Discard)
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if total := time.Since(start); total < 200*time.Millisecond {
+		t.Errorf("run took %v, want the 200ms warm-up first", total)
+	}
+	if _, elapsed := r.Result(); elapsed >= 200*time.Millisecond {
+		t.Errorf("measured %v, want the warm-up left out of the clock", elapsed)
+	}
+}
+
+func TestRunner_ProfileFollowsUsers(t *testing.T) {
+	var inFlight, peak int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&inFlight, 1)
+		for {
+			p := atomic.LoadInt64(&peak)
+			if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt64(&inFlight, -1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	uri, _ := config.NewURI(server.URL)
+	profile, err := config.ParseProfile("step:1,1,100ms,3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		URI:         uri,
+		Method:      config.MethodGET,
+		Concurrency: 3,
+		Duration:    80 * time.Millisecond,
+		Profile:     profile,
+	}
+
+	// During the first step a single user sends one request at a time.
+	r := New(cfg, io.Discard)
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := atomic.LoadInt64(&peak); got != 1 {
+		t.Errorf("peak in-flight requests = %d during the first step, want 1", got)
+	}
+
+	cfg.Duration = 500 * time.Millisecond
+	r = New(cfg, io.Discard)
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := atomic.LoadInt64(&peak); got != 3 {
+		t.Errorf("peak in-flight requests = %d, want the profile's 3 users", got)
+	}
+}
+
+func TestRunner_ProfileSharesBudget(t *testing.T) {
+	var requestCount int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requestCount, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	uri, _ := config.NewURI(server.URL)
+	profile, _ := config.ParseProfile("sine:1,4,200ms")
+	cfg := &config.Config{
+		URI:         uri,
+		Method:      config.MethodGET,
+		Concurrency: 4,
+		Requests:    17,
+		Profile:     profile,
+	}
+
+	r := New(cfg, io.Discard)
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := atomic.LoadInt64(&requestCount); got != 17 {
+		t.Errorf("expected 17 requests, got %d", got)
+	}
+}
+
+func TestRunner_ProfileEndsAtZeroUsers(t *testing.T) {
+	var requestCount int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requestCount, 1)
+		time.Sleep(10 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "profile.csv")
+	if err := os.WriteFile(path, []byte("0,2\n150ms,0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	uri, _ := config.NewURI(server.URL)
+	profile, err := config.ParseProfile("csv:" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The budget is far more than the profile sends before it drops to no
+	// users, so only the end of the profile can stop the run.
+	cfg := &config.Config{
+		URI:         uri,
+		Method:      config.MethodGET,
+		Concurrency: 2,
+		Requests:    100000,
+		Profile:     profile,
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- New(cfg, io.Discard).Run(context.Background()) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the run did not end after the profile dropped to zero users")
+	}
+	if got := atomic.LoadInt64(&requestCount); got == 0 {
+		t.Error("expected some requests before the profile ended")
+	}
+}
+
+func TestRunner_AdjustUsers(t *testing.T) {
+	var inFlight, peak int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&inFlight, 1)
+		for {
+			p := atomic.LoadInt64(&peak)
+			if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt64(&inFlight, -1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	uri, _ := config.NewURI(server.URL)
+	cfg := &config.Config{
+		URI:         uri,
+		Method:      config.MethodGET,
+		Concurrency: 1,
+		Duration:    300 * time.Millisecond,
+		Adjustable:  true,
+	}
+
+	r := New(cfg, io.Discard)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		r.SetUsers(4, "test")
+		r.SetMaxRPS(1000, "test")
+	}()
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := atomic.LoadInt64(&peak); got != 4 {
+		t.Errorf("peak in-flight requests = %d, want the 4 users set mid-run", got)
+	}
+	if r.Users() != 4 || r.MaxRPS() != 1000 {
+		t.Errorf("users = %d, max rps = %g; want 4 and 1000", r.Users(), r.MaxRPS())
+	}
+
+	timeline := r.Timeline()
+	if len(timeline) != 2 || timeline[0].Event != "users 1 -> 4" || timeline[1].Event != "max rps unlimited -> 1000" {
+		t.Fatalf("timeline = %+v", timeline)
+	}
+	if timeline[0].At < 100*time.Millisecond || timeline[0].Source != "test" {
+		t.Errorf("event = %+v, want one from test about 100ms in", timeline[0])
+	}
+}
+
+func TestRunner_NotAdjustable(t *testing.T) {
+	uri, _ := config.NewURI("http://127.0.0.1:1")
+	r := New(&config.Config{URI: uri, Concurrency: 1, Requests: 1}, io.Discard)
+	if err := r.SetUsers(2, "test"); err != ErrNotAdjustable {
+		t.Errorf("SetUsers err = %v, want ErrNotAdjustable", err)
+	}
+	if err := r.SetMaxRPS(2, "test"); err != ErrNotAdjustable {
+		t.Errorf("SetMaxRPS err = %v, want ErrNotAdjustable", err)
+	}
+	if err := r.Pause("test"); err != ErrNotAdjustable {
+		t.Errorf("Pause err = %v, want ErrNotAdjustable", err)
+	}
+	if err := r.Resume("test"); err != ErrNotAdjustable {
+		t.Errorf("Resume err = %v, want ErrNotAdjustable", err)
+	}
+}
+
+func TestRunner_AdjustChecksFirst(t *testing.T) {
+	uri, _ := config.NewURI("http://127.0.0.1:1")
+	r := New(&config.Config{URI: uri, Concurrency: 1, Requests: 1, Adjustable: true}, io.Discard)
+
+	users, rps := 5, -1.0
+	if err := r.Adjust(&users, &rps, "test"); err == nil {
+		t.Fatal("expected an error for a negative rate")
+	}
+	if r.Users() != 1 || len(r.Timeline()) != 0 {
+		t.Errorf("users = %d, timeline = %+v; want nothing applied", r.Users(), r.Timeline())
+	}
+}
+
+func TestRunner_PauseResume(t *testing.T) {
+	var requestCount int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requestCount, 1)
+		time.Sleep(5 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	uri, _ := config.NewURI(server.URL)
+	cfg := &config.Config{
+		URI:         uri,
+		Method:      config.MethodGET,
+		Concurrency: 2,
+		Duration:    200 * time.Millisecond,
+		Adjustable:  true,
+	}
+
+	r := New(cfg, io.Discard)
+	if err := r.Pause("test"); err == nil {
+		t.Error("pausing before the run starts should fail")
+	}
+
+	paused := make(chan int64, 1)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		r.Pause("test")
+		time.Sleep(20 * time.Millisecond) // let requests in flight finish
+		before := atomic.LoadInt64(&requestCount)
+		time.Sleep(200 * time.Millisecond)
+		paused <- atomic.LoadInt64(&requestCount) - before
+		r.Resume("test")
+	}()
+
+	start := time.Now()
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	total := time.Since(start)
+
+	if n := <-paused; n != 0 {
+		t.Errorf("%d requests were sent while paused", n)
+	}
+	// The pause pushes the end of the run back and stays out of its duration.
+	if total < 400*time.Millisecond {
+		t.Errorf("run took %v, want the 200ms duration plus the pause", total)
+	}
+	if _, elapsed := r.Result(); elapsed < 200*time.Millisecond || elapsed > 350*time.Millisecond {
+		t.Errorf("measured %v, want about 200ms without the pause", elapsed)
+	}
+
+	timeline := r.Timeline()
+	if len(timeline) != 2 || timeline[0].Event != "paused" || timeline[1].Event != "resumed after 200ms" {
+		t.Errorf("timeline = %+v", timeline)
+	}
+}
+
+func TestRunner_PauseKeepsBudget(t *testing.T) {
+	var requestCount int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requestCount, 1)
+		time.Sleep(5 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	uri, _ := config.NewURI(server.URL)
+	cfg := &config.Config{URI: uri, Method: config.MethodGET, Concurrency: 2, Requests: 40, Adjustable: true}
+
+	r := New(cfg, io.Discard)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		if r.Pause("test") == nil {
+			time.Sleep(50 * time.Millisecond)
+			r.Resume("test")
+		}
+	}()
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := atomic.LoadInt64(&requestCount); got != 40 {
+		t.Errorf("expected all 40 requests after resuming, got %d", got)
+	}
+}
+
+func TestRunner_Stop(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	uri, _ := config.NewURI(server.URL)
+	cfg := &config.Config{URI: uri, Method: config.MethodGET, Concurrency: 2, Duration: time.Minute}
+
+	r := New(cfg, io.Discard)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		r.Stop("test")
+		r.Stop("test") // a second stop is ignored
+	}()
+
+	start := time.Now()
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("a requested stop should end the run normally, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("run took %v after being stopped", elapsed)
+	}
+	if snap, _ := r.Result(); snap.SuccessCount == 0 || snap.FailureCount != 0 {
+		t.Errorf("snapshot = %+v, want requests in flight to finish successfully", snap)
+	}
+	if tl := r.Timeline(); len(tl) != 1 || tl[0].Event != "stopped" || tl[0].Source != "test" {
+		t.Errorf("timeline = %+v, want one stop from test", tl)
+	}
+}
@@ -0,0 +1,74 @@
+package runner
+
+import (
+	"sync"
+
+	"github.com/EsteveSegura/BrickHauler/internal/config"
+)
+
+// targetPicker spreads requests across targets in proportion to their
+// weights using smooth weighted round-robin, so every window of
+// sum(weights) requests follows the configured mix exactly. In sequence
+// mode each virtual user walks the targets in order instead, leaving out
+// those with a zero weight.
+type targetPicker struct {
+	targets  []config.Target
+	sequence bool
+
+	mu      sync.Mutex
+	current []int
+	total   int
+}
+
+func newTargetPicker(targets []config.Target, sequence bool) *targetPicker {
+	if sequence {
+		var on []config.Target
+		for _, t := range targets {
+			if t.Weight > 0 {
+				on = append(on, t)
+			}
+		}
+		targets = on
+	}
+	p := &targetPicker{
+		targets:  targets,
+		sequence: sequence,
+		current:  make([]int, len(targets)),
+	}
+	for _, t := range targets {
+		p.total += t.Weight
+	}
+	return p
+}
+
+// next returns the target for a virtual user's step'th request.
+func (p *targetPicker) next(step int) *config.Target {
+	if p.sequence {
+		return &p.targets[step%len(p.targets)]
+	}
+	if len(p.targets) == 1 {
+		return &p.targets[0]
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	best := 0
+	for i, t := range p.targets {
+		p.current[i] += t.Weight
+		if p.current[i] > p.current[best] {
+			best = i
+		}
+	}
+	p.current[best] -= p.total
+	return &p.targets[best]
+}
+
+// iterationLength is the number of requests in one iteration of a virtual
+// user: a pass over the targets in sequence mode, otherwise one request.
+func (p *targetPicker) iterationLength() int {
+	if p.sequence {
+		return len(p.targets)
+	}
+	return 1
+}
@@ -0,0 +1,74 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/EsteveSegura/BrickHauler/internal/config"
+)
+
+func TestTargetPicker_Weights(t *testing.T) {
+	p := newTargetPicker([]config.Target{
+		{Name: "search", Weight: 7},
+		{Name: "item", Weight: 2},
+		{Name: "checkout", Weight: 1},
+	}, false)
+
+	counts := map[string]int{}
+	for i := 0; i < 100; i++ {
+		counts[p.next(i).Name]++
+	}
+
+	if counts["search"] != 70 || counts["item"] != 20 || counts["checkout"] != 10 {
+		t.Errorf("distribution = %v, want 70/20/10", counts)
+	}
+}
+
+func TestTargetPicker_Smooth(t *testing.T) {
+	p := newTargetPicker([]config.Target{
+		{Name: "a", Weight: 1},
+		{Name: "b", Weight: 1},
+	}, false)
+
+	// Equal weights alternate instead of bunching up.
+	prev := p.next(0).Name
+	for i := 0; i < 5; i++ {
+		cur := p.next(i).Name
+		if cur == prev {
+			t.Fatalf("target %q picked twice in a row", cur)
+		}
+		prev = cur
+	}
+}
+
+func TestTargetPicker_Sequence(t *testing.T) {
+	p := newTargetPicker([]config.Target{
+		{Name: "login", Weight: 5},
+		{Name: "browse", Weight: 1},
+		{Name: "logout", Weight: 1},
+	}, true)
+
+	want := []string{"login", "browse", "logout", "login"}
+	for i, w := range want {
+		if got := p.next(i).Name; got != w {
+			t.Errorf("next(%d) = %q, want %q", i, got, w)
+		}
+	}
+}
+
+func TestTargetPicker_SequenceSkipsZeroWeight(t *testing.T) {
+	p := newTargetPicker([]config.Target{
+		{Name: "login", Weight: 1},
+		{Name: "beacon", Weight: 0},
+		{Name: "logout", Weight: 1},
+	}, true)
+
+	if n := p.iterationLength(); n != 2 {
+		t.Errorf("iterationLength() = %d, want 2", n)
+	}
+	want := []string{"login", "logout", "login"}
+	for i, w := range want {
+		if got := p.next(i).Name; got != w {
+			t.Errorf("next(%d) = %q, want %q", i, got, w)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Package scenario reads and writes scenario files: JSON documents that
+// describe the requests of a load test run.
+package scenario
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/EsteveSegura/BrickHauler/internal/config"
+)
+
+// Modes decide how requests are spread across targets.
+const (
+	// ModeWeighted picks targets in proportion to their weights.
+	ModeWeighted = "weighted"
+	// ModeSequence has every virtual user walk the targets in order,
+	// like a recorded user session.
+	ModeSequence = "sequence"
+)
+
+// Scenario is the on-disk description of a multi-target run.
+type Scenario struct {
+	Mode    string   `json:"mode,omitempty"`
+	Targets []Target `json:"targets"`
+}
+
+// Target is one request template in a scenario file.
+type Target struct {
+	Name    string            `json:"name,omitempty"`
+	Method  string            `json:"method,omitempty"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	Cookies []string          `json:"cookies,omitempty"`
+	// Weight is the relative share of requests sent to this target.
+	// Omitted means 1; zero turns the target off.
+	Weight *int `json:"weight,omitempty"`
+	// ThinkTime is how long the virtual user pauses after this request.
+	ThinkTime Duration `json:"think_time,omitempty"`
+}
+
+// Duration is a time.Duration written as a string such as "1.5s".
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"1.5s\"")
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Load reads a scenario file from disk.
+func Load(path string) (*Scenario, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading scenario: %w", err)
+	}
+	defer f.Close()
+
+	s, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading scenario %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Parse decodes a scenario from JSON.
+func Parse(r io.Reader) (*Scenario, error) {
+	var s Scenario
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&s); err != nil {
+		return nil, err
+	}
+	if len(s.Targets) == 0 {
+		return nil, fmt.Errorf("scenario has no targets")
+	}
+	if s.Mode != "" && s.Mode != ModeWeighted && s.Mode != ModeSequence {
+		return nil, fmt.Errorf("invalid mode %q: must be %s or %s", s.Mode, ModeWeighted, ModeSequence)
+	}
+	return &s, nil
+}
+
+// Sequential reports whether virtual users walk the targets in order.
+func (s *Scenario) Sequential() bool {
+	return s.Mode == ModeSequence
+}
+
+// Write encodes the scenario as indented JSON.
+func (s *Scenario) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// ConfigTargets converts the scenario into validated config targets.
+func (s *Scenario) ConfigTargets() ([]config.Target, error) {
+	targets := make([]config.Target, 0, len(s.Targets))
+	for i, t := range s.Targets {
+		ct, err := t.configTarget()
+		if err != nil {
+			return nil, fmt.Errorf("target %d: %w", i+1, err)
+		}
+		targets = append(targets, ct)
+	}
+	return targets, nil
+}
+
+func (t Target) configTarget() (config.Target, error) {
+	method := t.Method
+	if method == "" {
+		method = "GET"
+	}
+	parsedMethod, err := config.ParseHTTPMethod(method)
+	if err != nil {
+		return config.Target{}, err
+	}
+
+	uri, err := config.NewURI(t.URL)
+	if err != nil {
+		return config.Target{}, err
+	}
+
+	name := t.Name
+	if name == "" {
+		name = parsedMethod.String() + " " + t.URL
+	}
+
+	weight := 1
+	if t.Weight != nil {
+		weight = *t.Weight
+	}
+
+	var headers http.Header
+	if len(t.Headers) > 0 {
+		headers = make(http.Header, len(t.Headers))
+		for k, v := range t.Headers {
+			headers.Set(k, v)
+		}
+	}
+
+	var body []byte
+	if t.Body != "" {
+		body = []byte(t.Body)
+	}
+
+	cookies, err := config.ParseCookies(t.Cookies)
+	if err != nil {
+		return config.Target{}, err
+	}
+
+	if t.ThinkTime < 0 {
+		return config.Target{}, fmt.Errorf("think time cannot be negative")
+	}
+
+	return config.Target{
+		Name:      name,
+		Method:    parsedMethod,
+		URI:       uri,
+		Headers:   headers,
+		Body:      body,
+		Cookies:   cookies,
+		Weight:    weight,
+		ThinkTime: time.Duration(t.ThinkTime),
+	}, nil
+}
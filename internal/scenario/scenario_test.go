@@ -0,0 +1,148 @@
+package scenario
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EsteveSegura/BrickHauler/internal/config"
+)
+
+const sample = `{
+  "targets": [
+    {"name": "search", "url": "https://shop.example/search?q=brick", "weight": 70},
+    {"name": "item", "url": "https://shop.example/item/42", "weight": 20,
+     "headers": {"accept": "application/json"}},
+    {"method": "post", "url": "https://shop.example/checkout", "weight": 10,
+     "body": "{\"cart\":1}"}
+  ]
+}`
+
+func TestParse_ConfigTargets(t *testing.T) {
+	s, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	targets, err := s.ConfigTargets()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(targets) != 3 {
+		t.Fatalf("got %d targets, want 3", len(targets))
+	}
+
+	if targets[0].Method != config.MethodGET || targets[0].Weight != 70 {
+		t.Errorf("targets[0] = %+v", targets[0])
+	}
+	if got := targets[1].Headers.Get("Accept"); got != "application/json" {
+		t.Errorf("targets[1] Accept header = %q", got)
+	}
+	if targets[2].Name != "POST https://shop.example/checkout" {
+		t.Errorf("targets[2].Name = %q", targets[2].Name)
+	}
+	if string(targets[2].Body) != `{"cart":1}` {
+		t.Errorf("targets[2].Body = %q", targets[2].Body)
+	}
+}
+
+func TestConfigTargets_Weights(t *testing.T) {
+	s, err := Parse(strings.NewReader(`{"targets": [
+    {"url": "https://a.test/on"},
+    {"url": "https://a.test/off", "weight": 0}
+  ]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	targets, err := s.ConfigTargets()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if targets[0].Weight != 1 || targets[1].Weight != 0 {
+		t.Errorf("weights = %d, %d; want an omitted weight to be 1 and zero to stay 0", targets[0].Weight, targets[1].Weight)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"empty", `{"targets": []}`},
+		{"unknown field", `{"targets": [{"url": "https://a.test"}], "extra": 1}`},
+		{"malformed", `{"targets": [`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tt.input)); err == nil {
+				t.Errorf("Parse(%s) expected error", tt.input)
+			}
+		})
+	}
+}
+
+func TestConfigTargets_InvalidTarget(t *testing.T) {
+	s := &Scenario{Targets: []Target{{URL: "ftp://a.test"}}}
+	if _, err := s.ConfigTargets(); err == nil {
+		t.Error("expected error for unsupported URL scheme")
+	}
+
+	s = &Scenario{Targets: []Target{{Method: "FETCH", URL: "https://a.test"}}}
+	if _, err := s.ConfigTargets(); err == nil {
+		t.Error("expected error for invalid method")
+	}
+}
+
+func TestWrite_RoundTrip(t *testing.T) {
+	weight := 2
+	s := &Scenario{Targets: []Target{{Name: "home", URL: "https://a.test/", Weight: &weight}}}
+
+	var buf bytes.Buffer
+	if err := s.Write(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := Parse(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got.Targets) != 1 || got.Targets[0].Name != "home" || *got.Targets[0].Weight != 2 {
+		t.Errorf("round trip = %+v", got)
+	}
+}
+
+func TestParse_SequenceWithThinkTime(t *testing.T) {
+	s, err := Parse(strings.NewReader(`{
+  "mode": "sequence",
+  "targets": [
+    {"url": "https://a.test/login", "think_time": "1.5s", "cookies": ["sid=abc"]},
+    {"url": "https://a.test/logout"}
+  ]
+}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !s.Sequential() {
+		t.Error("Sequential() = false, want true")
+	}
+
+	targets, err := s.ConfigTargets()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if targets[0].ThinkTime != 1500*time.Millisecond {
+		t.Errorf("ThinkTime = %v, want 1.5s", targets[0].ThinkTime)
+	}
+	if len(targets[0].Cookies) != 1 || targets[0].Cookies[0].Name != "sid" {
+		t.Errorf("Cookies = %v", targets[0].Cookies)
+	}
+
+	if _, err := Parse(strings.NewReader(`{"mode": "random", "targets": [{"url": "https://a.test"}]}`)); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+	if _, err := Parse(strings.NewReader(`{"targets": [{"url": "https://a.test", "think_time": 5}]}`)); err == nil {
+		t.Error("expected error for numeric think time")
+	}
+}
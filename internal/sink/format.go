@@ -0,0 +1,124 @@
+package sink
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// targetStats is what every format reports for a target.
+type targetStats struct {
+	name      string
+	requests  int64
+	failures  int64
+	rps       float64
+	latencies []latency
+}
+
+type latency struct {
+	name string
+	ms   float64
+}
+
+func stats(iv Interval) []targetStats {
+	names := make([]string, 0, len(iv.Targets))
+	for name := range iv.Targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := make([]targetStats, 0, len(names))
+	for _, name := range names {
+		snap := iv.Targets[name]
+		t := targetStats{
+			name:     name,
+			requests: snap.TotalRequests(),
+			failures: snap.FailureCount,
+			rps:      float64(snap.TotalRequests()) / iv.Length.Seconds(),
+		}
+		if snap.SuccessCount > 0 {
+			t.latencies = []latency{
+				{"avg", ms(snap.AverageTime())},
+				{"p50", ms(snap.Percentile(50))},
+				{"p95", ms(snap.Percentile(95))},
+				{"p99", ms(snap.Percentile(99))},
+				{"max", ms(snap.Percentile(100))},
+			}
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// encodeStatsD formats iv as StatsD lines. Request counts are counters,
+// the rest gauges, with latencies in milliseconds:
+//
+//	brickhauler.users:10|g
+//	brickhauler.checkout.requests:120|c
+//	brickhauler.checkout.latency.p95:84.2|g
+func encodeStatsD(prefix string, iv Interval) []string {
+	lines := []string{fmt.Sprintf("%s.users:%d|g", prefix, iv.Users)}
+	for _, t := range stats(iv) {
+		base := prefix + "." + statsdName(t.name)
+		lines = append(lines,
+			fmt.Sprintf("%s.requests:%d|c", base, t.requests),
+			fmt.Sprintf("%s.failures:%d|c", base, t.failures),
+			fmt.Sprintf("%s.rps:%s|g", base, formatFloat(t.rps)),
+		)
+		for _, l := range t.latencies {
+			lines = append(lines, fmt.Sprintf("%s.latency.%s:%s|g", base, l.name, formatFloat(l.ms)))
+		}
+	}
+	return lines
+}
+
+// statsdName turns a target name into a single metric name segment.
+func statsdName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// encodeInflux formats iv as InfluxDB line protocol, one line per target
+// tagged with its name plus one for the load, stamped with the end of the
+// interval:
+//
+//	brickhauler,target=checkout requests=120i,failures=3i,rps=12,latency_p95_ms=84.2 1700000000000000000
+//	brickhauler_load users=10i 1700000000000000000
+func encodeInflux(prefix string, iv Interval) []string {
+	ts := strconv.FormatInt(iv.End.UnixNano(), 10)
+	measurement := influxEscaper.Replace(prefix)
+
+	var lines []string
+	for _, t := range stats(iv) {
+		fields := []string{
+			fmt.Sprintf("requests=%di", t.requests),
+			fmt.Sprintf("failures=%di", t.failures),
+			"rps=" + formatFloat(t.rps),
+		}
+		for _, l := range t.latencies {
+			fields = append(fields, fmt.Sprintf("latency_%s_ms=%s", l.name, formatFloat(l.ms)))
+		}
+		lines = append(lines, fmt.Sprintf("%s,target=%s %s %s",
+			measurement, influxEscaper.Replace(t.name), strings.Join(fields, ","), ts))
+	}
+	lines = append(lines, fmt.Sprintf("%s_load users=%di %s", measurement, iv.Users, ts))
+	return lines
+}
+
+// influxEscaper escapes measurement names and tag values.
+var influxEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
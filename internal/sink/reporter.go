@@ -0,0 +1,130 @@
+package sink
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/EsteveSegura/BrickHauler/internal/metrics"
+)
+
+// Reporter aggregates the samples of a run over fixed intervals and sends
+// each interval to every sink. It implements metrics.StartObserver, so a
+// runner starts it when the measured run begins.
+type Reporter struct {
+	sinks    []*Sink
+	interval time.Duration
+	users    func() int
+
+	mu      sync.Mutex
+	current *metrics.Group
+	start   time.Time
+	started bool
+	err     error
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewReporter returns a reporter sending to sinks every interval. users
+// reports the current number of virtual users.
+func NewReporter(sinks []*Sink, interval time.Duration, users func() int) *Reporter {
+	return &Reporter{
+		sinks:    sinks,
+		interval: interval,
+		users:    users,
+		current:  metrics.NewGroup(),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Observe adds a completed request to the current interval.
+func (r *Reporter) Observe(s metrics.Sample) {
+	// Record under the lock so the sample cannot miss the interval a
+	// concurrent flush is sending.
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m := r.current.Get(s.Target)
+	if s.Failed() {
+		m.RecordFailure()
+	} else {
+		m.RecordSuccess(s.Duration)
+	}
+}
+
+// Start begins the first interval and sends one every tick until Stop.
+// Calls after the first do nothing.
+func (r *Reporter) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.started {
+		return
+	}
+	r.started = true
+	r.start = time.Now()
+
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				r.flush()
+			}
+		}
+	}()
+}
+
+// Stop sends the last, partial interval, if Start was called, and closes
+// the sinks. It returns the first error met while sending.
+func (r *Reporter) Stop() error {
+	r.mu.Lock()
+	started := r.started
+	r.mu.Unlock()
+
+	if started {
+		close(r.stop)
+		<-r.done
+		r.flush()
+	}
+
+	var errs []error
+	for _, s := range r.sinks {
+		errs = append(errs, s.Close())
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return errors.Join(append([]error{r.err}, errs...)...)
+}
+
+// flush starts a new interval and sends the one that just ended.
+func (r *Reporter) flush() {
+	now := time.Now()
+
+	r.mu.Lock()
+	group := r.current
+	length := now.Sub(r.start)
+	r.current = metrics.NewGroup()
+	r.start = now
+	r.mu.Unlock()
+
+	if length <= 0 {
+		return
+	}
+	iv := Interval{End: now, Length: length, Users: r.users(), Targets: group.Snapshot()}
+	for _, s := range r.sinks {
+		if err := s.Send(iv); err != nil {
+			r.mu.Lock()
+			if r.err == nil {
+				r.err = err
+			}
+			r.mu.Unlock()
+		}
+	}
+}
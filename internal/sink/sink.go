@@ -0,0 +1,129 @@
+// Package sink streams per-interval aggregates of a run to metric stacks
+// over UDP, in the StatsD or InfluxDB line protocol, so results land next
+// to application metrics without a scraping setup.
+package sink
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/EsteveSegura/BrickHauler/internal/metrics"
+)
+
+// Format is the wire protocol of a sink.
+type Format string
+
+const (
+	FormatStatsD Format = "statsd"
+	FormatInflux Format = "influx"
+)
+
+// DefaultPrefix names the metrics unless the sink sets its own.
+const DefaultPrefix = "brickhauler"
+
+// maxPacket keeps datagrams under the usual 1500 byte MTU.
+const maxPacket = 1400
+
+// Interval is the aggregate of the requests completed during one
+// reporting interval.
+type Interval struct {
+	End    time.Time
+	Length time.Duration
+	// Users is the number of virtual users at the end of the interval.
+	Users int
+	// Targets holds the metrics of each target.
+	Targets map[string]metrics.Snapshot
+}
+
+// Sink sends intervals to one address.
+type Sink struct {
+	format Format
+	prefix string
+	conn   net.Conn
+}
+
+// Dial parses a sink specification such as statsd://127.0.0.1:8125 or
+// influx://127.0.0.1:8089?prefix=loadtest and opens its UDP socket.
+func Dial(spec string) (*Sink, error) {
+	u, err := url.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sink %q: %w", spec, err)
+	}
+
+	format := Format(u.Scheme)
+	switch format {
+	case FormatStatsD, FormatInflux:
+	case "influxdb":
+		format = FormatInflux
+	default:
+		return nil, fmt.Errorf("invalid sink %q: scheme must be statsd or influx", spec)
+	}
+	if u.Port() == "" {
+		return nil, fmt.Errorf("invalid sink %q: address must include host and port", spec)
+	}
+
+	prefix := u.Query().Get("prefix")
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	conn, err := net.Dial("udp", u.Host)
+	if err != nil {
+		return nil, fmt.Errorf("sink %s: %w", spec, err)
+	}
+	return &Sink{format: format, prefix: prefix, conn: conn}, nil
+}
+
+// Send writes iv to the sink, packing as many lines into each datagram as
+// fit.
+func (s *Sink) Send(iv Interval) error {
+	var lines []string
+	if s.format == FormatStatsD {
+		lines = encodeStatsD(s.prefix, iv)
+	} else {
+		lines = encodeInflux(s.prefix, iv)
+	}
+
+	for _, packet := range packets(lines, maxPacket) {
+		if _, err := s.conn.Write(packet); err != nil {
+			return fmt.Errorf("sink %s: %w", s, err)
+		}
+	}
+	return nil
+}
+
+// Close closes the UDP socket.
+func (s *Sink) Close() error {
+	return s.conn.Close()
+}
+
+// String describes the sink for messages.
+func (s *Sink) String() string {
+	return string(s.format) + "://" + s.conn.RemoteAddr().String()
+}
+
+// packets joins lines with newlines into datagrams of at most size bytes.
+// A line longer than size gets a datagram of its own.
+func packets(lines []string, size int) [][]byte {
+	var (
+		out [][]byte
+		cur strings.Builder
+	)
+	for _, line := range lines {
+		if cur.Len() > 0 && cur.Len()+1+len(line) > size {
+			out = append(out, []byte(cur.String()))
+			cur.Reset()
+		}
+		if cur.Len() > 0 {
+			cur.WriteByte('\n')
+		}
+		cur.WriteString(line)
+	}
+	if cur.Len() > 0 {
+		out = append(out, []byte(cur.String()))
+	}
+	return out
+}
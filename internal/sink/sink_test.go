@@ -0,0 +1,182 @@
+package sink
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EsteveSegura/BrickHauler/internal/metrics"
+)
+
+// listen opens a local UDP listener and returns its address and a function
+// reading everything received until the socket goes quiet.
+func listen(t *testing.T) (string, func() string) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn.LocalAddr().String(), func() string {
+		var b strings.Builder
+		buf := make([]byte, 65536)
+		for {
+			conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				return b.String()
+			}
+			b.Write(buf[:n])
+			b.WriteByte('\n')
+		}
+	}
+}
+
+func testInterval() Interval {
+	m := metrics.New(0)
+	m.RecordSuccess(100 * time.Millisecond)
+	m.RecordSuccess(300 * time.Millisecond)
+	m.RecordFailure()
+	return Interval{
+		End:     time.Unix(1700000000, 0),
+		Length:  2 * time.Second,
+		Users:   10,
+		Targets: map[string]metrics.Snapshot{"GET /cart, v2": m.Snapshot()},
+	}
+}
+
+func TestDial(t *testing.T) {
+	for _, spec := range []string{
+		"graphite://127.0.0.1:2003",
+		"statsd://127.0.0.1",
+		"statsd://",
+		"127.0.0.1:8125",
+	} {
+		if _, err := Dial(spec); err == nil {
+			t.Errorf("Dial(%q) should fail", spec)
+		}
+	}
+
+	s, err := Dial("influxdb://127.0.0.1:8089?prefix=loadtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.format != FormatInflux || s.prefix != "loadtest" {
+		t.Errorf("got format %q prefix %q", s.format, s.prefix)
+	}
+}
+
+func TestSink_StatsD(t *testing.T) {
+	addr, read := listen(t)
+	s, err := Dial("statsd://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Send(testInterval()); err != nil {
+		t.Fatal(err)
+	}
+	got := read()
+	for _, want := range []string{
+		"brickhauler.users:10|g\n",
+		"brickhauler.GET__cart__v2.requests:3|c\n",
+		"brickhauler.GET__cart__v2.failures:1|c\n",
+		"brickhauler.GET__cart__v2.rps:1.5|g\n",
+		"brickhauler.GET__cart__v2.latency.avg:200|g\n",
+		"brickhauler.GET__cart__v2.latency.max:300|g\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestSink_Influx(t *testing.T) {
+	addr, read := listen(t)
+	s, err := Dial("influx://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Send(testInterval()); err != nil {
+		t.Fatal(err)
+	}
+	want := `brickhauler,target=GET\ /cart\,\ v2 requests=3i,failures=1i,rps=1.5,` +
+		`latency_avg_ms=200,latency_p50_ms=300,latency_p95_ms=300,latency_p99_ms=300,latency_max_ms=300 1700000000000000000` + "\n" +
+		"brickhauler_load users=10i 1700000000000000000\n"
+	if got := read(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPackets(t *testing.T) {
+	lines := []string{"aaaa", "bbbb", "cccc", strings.Repeat("d", 20)}
+	got := packets(lines, 10)
+	want := []string{"aaaa\nbbbb", "cccc", strings.Repeat("d", 20)}
+	if len(got) != len(want) {
+		t.Fatalf("got %d packets, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if string(got[i]) != want[i] {
+			t.Errorf("packet %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestReporter(t *testing.T) {
+	addr, read := listen(t)
+	s, err := Dial("statsd://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewReporter([]*Sink{s}, 50*time.Millisecond, func() int { return 4 })
+	r.Start()
+	r.Observe(metrics.Sample{Target: "home", Method: "GET", Status: 200, Duration: 10 * time.Millisecond})
+	r.Observe(metrics.Sample{Target: "home", Method: "GET", Status: 500, Error: metrics.ErrorHTTP})
+	time.Sleep(80 * time.Millisecond)
+	r.Observe(metrics.Sample{Target: "home", Method: "GET", Status: 200, Duration: 10 * time.Millisecond})
+	if err := r.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first interval holds two requests; the partial last one, sent
+	// on Stop, holds the third.
+	got := read()
+	if strings.Count(got, "brickhauler.users:4|g") < 2 {
+		t.Errorf("want at least two intervals, got:\n%s", got)
+	}
+	for _, want := range []string{"brickhauler.home.requests:2|c", "brickhauler.home.failures:1|c", "brickhauler.home.requests:1|c"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestReporter_StopWithoutStart(t *testing.T) {
+	addr, read := listen(t)
+	s, err := Dial("statsd://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewReporter([]*Sink{s}, time.Second, func() int { return 0 })
+	done := make(chan error, 1)
+	go func() { done <- r.Stop() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop blocked without Start")
+	}
+	if got := read(); got != "" {
+		t.Errorf("sent %q before the run started", got)
+	}
+}